@@ -0,0 +1,69 @@
+// Comando identity: genera le chiavi Ed25519 per la firma delle entry della Membership List.
+//
+//	identity ca                          nuova CA: CA_KEY (segreta) e TRUSTED_KEYS per i nodi
+//	identity issue [-ca chiave] -name ID chiave e certificato del nodo ID (CA_KEY di default)
+//
+// L'output è nel formato delle variabili d'ambiente lette dai nodi.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"Gossip/memberlist"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "ca":
+		publicKey, privateKey, err := memberlist.GenerateIdentityKey()
+		if err != nil {
+			log.Fatalf("[IDENTITY] Errore generazione chiave: %v", err)
+		}
+		fmt.Printf("CA_KEY=%s\n", encode(privateKey.Seed()))
+		fmt.Printf("TRUSTED_KEYS=%s\n", encode(publicKey))
+
+	case "issue":
+		flags := flag.NewFlagSet("issue", flag.ExitOnError)
+		encodedCA := flags.String("ca", os.Getenv("CA_KEY"), "chiave privata della CA in base64 (default CA_KEY)")
+		name := flags.String("name", "", "nome del nodo (node.id)")
+		flags.Parse(os.Args[2:])
+		if *name == "" {
+			usage()
+		}
+
+		seed, err := base64.StdEncoding.DecodeString(*encodedCA)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			log.Fatalf("[IDENTITY] Chiave della CA non valida (attesi %d byte in base64)", ed25519.SeedSize)
+		}
+		caKey := ed25519.NewKeyFromSeed(seed)
+
+		publicKey, privateKey, err := memberlist.GenerateIdentityKey()
+		if err != nil {
+			log.Fatalf("[IDENTITY] Errore generazione chiave: %v", err)
+		}
+		fmt.Printf("NODE_ID=%s\n", *name)
+		fmt.Printf("IDENTITY_KEY=%s\n", encode(privateKey.Seed()))
+		fmt.Printf("IDENTITY_CERTIFICATE=%s\n", encode(memberlist.IssueCertificate(caKey, *name, publicKey)))
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "uso: %s ca | issue [-ca chiave] -name <node.id>\n", os.Args[0])
+	os.Exit(2)
+}
+
+func encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,143 @@
+// Comando keys: gestione online delle chiavi di cifratura del cluster.
+//
+//	keys [-node host:port] [-keys k1,k2] list
+//	keys [-node host:port] [-keys k1,k2] install|use|remove <chiave base64>
+//
+// La richiesta viene inviata a un solo nodo, che la propaga a tutti i membri e
+// restituisce l'esito per nodo. Rotazione di una chiave compromessa:
+//
+//	keys -keys VECCHIA install NUOVA
+//	keys -keys VECCHIA use NUOVA
+//	keys -keys NUOVA remove VECCHIA
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"Gossip/internal/keyring"
+	"Gossip/internal/rotation"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// Tempo massimo per la propagazione dell'operazione a tutto il cluster (remove richiede due
+// giri: la verifica della chiave primaria su ogni membro e la rimozione, con eventuali tentativi)
+const commandTimeout = 1 * time.Minute
+
+func main() {
+	nodeAddress := flag.String("node", "127.0.0.1:9000", "indirizzo host:port di un nodo del cluster")
+	encodedKeys := flag.String("keys", os.Getenv("ENCRYPTION_KEYS"), "chiavi del cluster in base64, separate da virgole (default ENCRYPTION_KEYS)")
+	tlsFiles := transport.TLSFiles{}
+	flag.StringVar(&tlsFiles.CertFile, "tls-cert", os.Getenv("TLS_CERT_FILE"), "certificato client per il TLS mutuo (default TLS_CERT_FILE)")
+	flag.StringVar(&tlsFiles.KeyFile, "tls-key", os.Getenv("TLS_KEY_FILE"), "chiave del certificato client (default TLS_KEY_FILE)")
+	flag.StringVar(&tlsFiles.CAFile, "tls-ca", os.Getenv("TLS_CA_FILE"), "CA dei certificati dei nodi (default TLS_CA_FILE)")
+	serverName := flag.String("tls-server-name", "", "node.id atteso nel certificato del nodo (vuoto = verifica solo la CA)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "uso: %s [opzioni] list | install|use|remove <chiave base64>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	op, key, decodedKey := parseCommand(flag.Args())
+
+	// ✅ Il nodo accetta solo traffico cifrato con una chiave del cluster
+	keys, err := keyring.DecodeKeys(strings.Split(*encodedKeys, ","))
+	if err != nil || len(keys) == 0 {
+		log.Fatalf("[KEYRING] Chiavi del cluster non valide (flag -keys o ENCRYPTION_KEYS): %v", err)
+	}
+	clusterKeyring, err := keyring.New(keys)
+	if err != nil {
+		log.Fatalf("[KEYRING] %v", err)
+	}
+	if op == rotation.OpInstall || op == rotation.OpUse {
+		// ✅ Dopo use il nodo risponde già cifrando con la nuova chiave
+		if err := clusterKeyring.AddKey(decodedKey); err != nil {
+			log.Fatalf("[KEYRING] %v", err)
+		}
+	}
+	localTransport, err := transport.Listen("127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("[KEYRING] Errore apertura porta locale: %v", err)
+	}
+	defer localTransport.Close()
+
+	// ✅ Se il cluster usa il TLS mutuo serve anche un certificato client emesso dalla sua CA
+	var nodeTransport transport.Transport = localTransport
+	if tlsFiles.CertFile != "" {
+		tlsTransport, err := transport.NewTLS(localTransport, tlsFiles, func(string) string { return *serverName })
+		if err != nil {
+			log.Fatalf("[KEYRING] %v", err)
+		}
+		nodeTransport = tlsTransport
+	}
+
+	request, err := json.Marshal(util.KeyMessage{Type: "key_command", Op: op, Key: key})
+	if err != nil {
+		log.Fatalf("[KEYRING] Errore serializzazione richiesta: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	responseData, err := transport.Exchange(ctx, transport.NewEncrypted(nodeTransport, clusterKeyring), *nodeAddress, request)
+	if err != nil {
+		log.Fatalf("[KEYRING] Nessuna risposta da %s: %v", *nodeAddress, err)
+	}
+
+	var response util.KeyMessage
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		log.Fatalf("[KEYRING] Risposta non valida da %s: %v", *nodeAddress, err)
+	}
+	if failed := printResults(op, response.Results); failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// ✅ Valida gli argomenti: operazione ed eventuale chiave (in base64 e decodificata)
+func parseCommand(args []string) (string, string, []byte) {
+	if len(args) == 1 && args[0] == rotation.OpList {
+		return rotation.OpList, "", nil
+	}
+	if len(args) == 2 {
+		switch args[0] {
+		case rotation.OpInstall, rotation.OpUse, rotation.OpRemove:
+			keys, err := keyring.DecodeKeys(args[1:])
+			if err != nil {
+				log.Fatalf("[KEYRING] %v", err)
+			}
+			return args[0], args[1], keys[0]
+		}
+	}
+	flag.Usage()
+	os.Exit(2)
+	return "", "", nil
+}
+
+// ✅ Stampa l'esito per nodo e restituisce il numero di nodi su cui l'operazione è fallita
+func printResults(op string, results map[string]util.KeyResult) int {
+	ids := make([]string, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	failed := 0
+	for _, id := range ids {
+		result := results[id]
+		if result.Error != "" {
+			failed++
+			fmt.Printf("%-20s ERRORE  %s\n", id, result.Error)
+			continue
+		}
+		fmt.Printf("%-20s OK      %s\n", id, strings.Join(result.Keys, " "))
+	}
+	fmt.Printf("%s: riuscita su %d nodi su %d\n", op, len(results)-failed, len(results))
+	return failed
+}
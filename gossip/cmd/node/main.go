@@ -1,90 +1,84 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
-	"Gossip/internal/failure" // <-- DECOMMENTA QUESTA RIGA
-	"Gossip/internal/gossip"
-	"Gossip/internal/leave" // <-- DECOMMENTA QUESTA RIGA
-	"Gossip/internal/membership"
+	"Gossip/internal/config"
 	"Gossip/internal/util"
+	"Gossip/memberlist"
 )
 
-func main() {
-	// ✅ Inizializza il random per le selezioni casuali
-	rand.Seed(time.Now().UnixNano())
+// Tempo massimo concesso all'invio dei messaggi LEAVE in chiusura
+const leaveTimeout = 5 * time.Second
 
-	// ✅ Lettura variabili d'ambiente
-	nodeID := os.Getenv("NODE_ID")
-	nodeIP := os.Getenv("NODE_IP")
-	nodePort := os.Getenv("NODE_PORT")
-	seedNodes := os.Getenv("SEED_NODES") // Nodi iniziali da contattare per bootstrap (facoltativo)
+func main() {
+	// ✅ Percorso del file di configurazione: flag -config, altrimenti CONFIG_PATH
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "percorso del file di configurazione YAML (default "+config.DefaultPath+")")
+	flag.Parse()
 
-	// ✅ Controllo parametri essenziali
-	if nodeID == "" || nodeIP == "" || nodePort == "" {
-		log.Fatal("NODE_ID, NODE_IP e NODE_PORT devono essere specificati come variabili d'ambiente.")
+	// ✅ Caricamento configurazione (file YAML + override da variabili d'ambiente)
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[BOOTSTRAP] Configurazione non valida: %v", err)
+	}
+	if err := util.SetLogLevel(cfg.Logging.Level); err != nil {
+		log.Fatalf("[BOOTSTRAP] Configurazione non valida: %v", err)
 	}
 
-	// ✅ Creazione Membership List vuota
-	localMembership := membership.NewMembershipList()
+	// ✅ Creazione del nodo (Membership List, Prober e failure detector)
+	node, err := memberlist.Create(cfg.MemberlistConfig())
+	if err != nil {
+		log.Fatalf("[BOOTSTRAP] %v", err)
+	}
+	util.Info(fmt.Sprintf("[BOOTSTRAP] Nodo %s (%s:%s) inizializzato.", cfg.Node.ID, cfg.Node.IP, cfg.Node.Port))
 
-	// ✅ Inserisce sé stesso nella Membership List
-	selfNode := util.NodeStatus{
-		ID:       fmt.Sprintf("%s:%s", nodeIP, nodePort), // Nodo unico sulla rete
-		IP:       nodeIP,
-		Port:     nodePort,
-		Status:   "alive",
-		LastSeen: time.Now().Format(time.RFC3339),
+	// ✅ Avvio server UDP e TCP, ciclo di gossip, Prober e failure detector
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := node.Start(ctx); err != nil {
+		log.Fatalf("[BOOTSTRAP] %v", err)
 	}
-	localMembership.AddOrUpdateNode(selfNode)
-	log.Printf("[BOOTSTRAP] Nodo %s (%s:%s) inizializzato.\n", nodeID, nodeIP, nodePort)
 
-	// ✅ Aggiunge SEED_NODES (se presenti)
-	if seedNodes != "" {
-		nodes := strings.Split(seedNodes, ",")
-		for _, nodeStr := range nodes {
-			parts := strings.Split(nodeStr, ":")
-			if len(parts) == 2 {
-				seedNode := util.NodeStatus{
-					ID:       fmt.Sprintf("%s:%s", parts[0], parts[1]), // ID = "ip:port"
-					IP:       parts[0],
-					Port:     parts[1],
-					Status:   "alive",
-					LastSeen: time.Now().Format(time.RFC3339),
-				}
-				// Aggiunge solo se diverso da sé stesso
-				if seedNode.ID != selfNode.ID {
-					localMembership.AddOrUpdateNode(seedNode)
-				}
-			}
+	// ✅ JOIN tramite i seed (se presenti): se nessuno risponde si riprova in background
+	if len(cfg.Node.Seeds) > 0 {
+		_, err := node.Join(cfg.Node.Seeds)
+		if errors.Is(err, memberlist.ErrNameConflict) || errors.Is(err, memberlist.ErrIdentityRejected) {
+			// Nessun LEAVE: il nome appartiene a un altro nodo attivo, o il cluster non ci riconosce
+			node.Shutdown()
+			log.Fatalf("[JOIN] %v", err)
+		}
+		if err != nil {
+			util.Warn(fmt.Sprintf("[JOIN] JOIN fallito: %v. Nuovi tentativi in background.", err))
+			node.JoinInBackground(cfg.Node.Seeds)
 		}
-		log.Printf("[BOOTSTRAP] Aggiunti %d SEED_NODES iniziali.\n", len(nodes))
 	} else {
-		log.Println("[BOOTSTRAP] Nessun SEED_NODES definito. Nodo isolato, in attesa di gossip.")
+		util.Info("[BOOTSTRAP] Nessun SEED_NODES definito. Nodo isolato, in attesa di gossip.")
 	}
 
-	// ✅ Avvio server UDP per ricezione gossip
-	go gossip.StartUDPServer(nodePort, localMembership, selfNode)
-
-	// ✅ Avvio ciclo gossip periodico (push-pull + heartbeat)
-	go gossip.StartGossipCycle(nodeID, nodeIP, nodePort, localMembership, selfNode)
-
-	// ✅ Avvio failure detector
-	go failure.StartFailureDetector(localMembership, selfNode) // <-- DECOMMENTA QUESTA RIGA
-
-	// ✅ Gestione LEAVE in chiusura
+	// ✅ SIGHUP ricarica i certificati TLS, SIGINT/SIGTERM avviano la chiusura con LEAVE
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-	<-signalChan
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range signalChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		if err := node.ReloadTLS(); err != nil {
+			util.Warn(fmt.Sprintf("[BOOTSTRAP] Ricaricamento TLS fallito: %v", err))
+		}
+	}
 
-	log.Println("[EXIT] Ricevuto segnale di interruzione. Comunicazione LEAVE alla rete.")
-	leave.SendLeaveMessage(localMembership, selfNode) // <-- DECOMMENTA QUESTA RIGA
-	log.Println("[EXIT] Nodo arrestato correttamente.")
+	util.Info("[EXIT] Ricevuto segnale di interruzione. Comunicazione LEAVE alla rete.")
+	if err := node.Leave(leaveTimeout); err != nil {
+		util.Warn(fmt.Sprintf("[EXIT] %v", err))
+	}
+	node.Shutdown()
+	util.Info("[EXIT] Nodo arrestato correttamente.")
 }
@@ -0,0 +1,73 @@
+// Package clock astrae il tempo usato dal nodo (ticker, timeout, backoff e timestamp
+// LastSeen), così test e simulatore possono farlo avanzare in modo virtuale invece di
+// attendere davvero.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// ✅ Clock: sorgente del tempo per i cicli del nodo
+type Clock interface {
+	// Istante corrente
+	Now() time.Time
+	// Canale che riceve l'istante di scadenza dopo d (come time.After)
+	After(d time.Duration) <-chan time.Time
+	// Ticker con periodo d (come time.NewTicker; d deve essere positivo)
+	NewTicker(d time.Duration) *Ticker
+}
+
+// ✅ Ticker restituito da Clock.NewTicker
+type Ticker struct {
+	C    <-chan time.Time // Riceve un istante ad ogni periodo (i tick non letti vengono scartati)
+	stop func()
+}
+
+// ✅ Crea un Ticker che riceve i tick da c e si ferma chiamando stop: serve a chi
+// implementa Clock fuori da questo pacchetto (es. time.NewTicker o un orologio di test)
+func NewTicker(c <-chan time.Time, stop func()) *Ticker {
+	return &Ticker{C: c, stop: stop}
+}
+
+// ✅ Ferma il ticker (il canale C non viene chiuso, come per time.Ticker)
+func (t *Ticker) Stop() {
+	t.stop()
+}
+
+// ✅ Orologio reale basato sul pacchetto time
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) *Ticker {
+	ticker := time.NewTicker(d)
+	return NewTicker(ticker.C, ticker.Stop)
+}
+
+// ✅ Come context.WithTimeout, ma la scadenza viene misurata su clk: con un orologio
+// virtuale il contesto scade solo quando l'orologio avanza oltre d
+func WithTimeout(parent context.Context, clk Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if clk == Real {
+		return context.WithTimeout(parent, d)
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	expired := clk.After(d)
+	go func() {
+		select {
+		case <-expired:
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
@@ -0,0 +1,181 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// ✅ Virtual: orologio che avanza solo quando viene chiamato Advance.
+// I timer scaduti vengono eseguiti in ordine di scadenza (a parità, in ordine di creazione)
+// e durante l'esecuzione Now restituisce la scadenza del timer corrente
+type Virtual struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers timerHeap
+	seq    uint64 // Ordine di creazione dei timer
+}
+
+// ✅ Timer di un orologio virtuale: invia su ch oppure esegue fn alla scadenza
+type virtualTimer struct {
+	when    time.Time
+	period  time.Duration // > 0 per i ticker
+	ch      chan time.Time
+	fn      func()
+	seq     uint64
+	stopped bool
+}
+
+// Costruttore: crea un orologio virtuale fermo all'istante start
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+// ✅ Istante virtuale corrente
+func (c *Virtual) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// ✅ Canale che riceve l'istante virtuale dopo d
+func (c *Virtual) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.schedule(&virtualTimer{when: c.Now().Add(d), ch: ch})
+	return ch
+}
+
+// ✅ Ticker virtuale con periodo d
+func (c *Virtual) NewTicker(d time.Duration) *Ticker {
+	if d <= 0 {
+		panic("clock: periodo del ticker non positivo")
+	}
+	ch := make(chan time.Time, 1)
+	timer := &virtualTimer{when: c.Now().Add(d), period: d, ch: ch}
+	c.schedule(timer)
+	return NewTicker(ch, func() { c.stop(timer) })
+}
+
+// ✅ Esegue fn quando l'orologio raggiunge Now()+d (fn viene chiamata da Advance, senza lock)
+func (c *Virtual) AfterFunc(d time.Duration, fn func()) {
+	c.schedule(&virtualTimer{when: c.Now().Add(d), fn: fn})
+}
+
+// ✅ Scadenza del prossimo timer attivo (false se non ce ne sono)
+func (c *Virtual) Next() (time.Time, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for len(c.timers) > 0 {
+		if timer := c.timers[0]; !timer.stopped {
+			return timer.when, true
+		}
+		heap.Pop(&c.timers)
+	}
+	return time.Time{}, false
+}
+
+// ✅ Indica se ci sono timer già scaduti (es. After(0)) non ancora eseguiti: li esegue
+// la prossima chiamata ad Advance o AdvanceTo, anche senza far avanzare l'orologio
+func (c *Virtual) Due() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for len(c.timers) > 0 {
+		if timer := c.timers[0]; !timer.stopped {
+			return !timer.when.After(c.now)
+		}
+		heap.Pop(&c.timers)
+	}
+	return false
+}
+
+// ✅ Porta l'orologio a Now()+d eseguendo in ordine tutti i timer che scadono nel frattempo
+func (c *Virtual) Advance(d time.Duration) {
+	c.AdvanceTo(c.Now().Add(d))
+}
+
+// ✅ Porta l'orologio all'istante target (mai indietro) eseguendo i timer scaduti
+func (c *Virtual) AdvanceTo(target time.Time) {
+	for c.fireNext(target) {
+	}
+
+	c.mutex.Lock()
+	if target.After(c.now) {
+		c.now = target
+	}
+	c.mutex.Unlock()
+}
+
+// ✅ Esegue il primo timer con scadenza entro target (false se non ce ne sono)
+func (c *Virtual) fireNext(target time.Time) bool {
+	c.mutex.Lock()
+	if len(c.timers) == 0 || c.timers[0].when.After(target) {
+		c.mutex.Unlock()
+		return false
+	}
+
+	timer := heap.Pop(&c.timers).(*virtualTimer)
+	if timer.stopped {
+		c.mutex.Unlock()
+		return true
+	}
+	if timer.when.After(c.now) {
+		c.now = timer.when
+	}
+	now := c.now
+	if timer.period > 0 {
+		// Ticker: si riprogramma per il periodo successivo
+		timer.when = timer.when.Add(timer.period)
+		c.pushLocked(timer)
+	}
+	c.mutex.Unlock()
+
+	if timer.fn != nil {
+		timer.fn()
+		return true
+	}
+	select {
+	case timer.ch <- now:
+	default: // Tick non letto: viene scartato come in time.Ticker
+	}
+	return true
+}
+
+func (c *Virtual) schedule(timer *virtualTimer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pushLocked(timer)
+}
+
+func (c *Virtual) pushLocked(timer *virtualTimer) {
+	c.seq++
+	timer.seq = c.seq
+	heap.Push(&c.timers, timer)
+}
+
+func (c *Virtual) stop(timer *virtualTimer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	timer.stopped = true
+}
+
+// ✅ Coda di priorità dei timer (scadenza, poi ordine di creazione)
+type timerHeap []*virtualTimer
+
+func (h timerHeap) Len() int { return len(h) }
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].when.Before(h[j].when)
+}
+func (h timerHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *timerHeap) Push(x any)   { *h = append(*h, x.(*virtualTimer)) }
+func (h *timerHeap) Pop() any {
+	old := *h
+	timer := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return timer
+}
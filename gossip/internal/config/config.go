@@ -0,0 +1,364 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"Gossip/internal/keyring"
+	"Gossip/memberlist"
+)
+
+// Percorso di default del file di configurazione (relativo alla working directory)
+const DefaultPath = "config/config.yml"
+
+// ✅ Configurazione completa del nodo (file YAML + override da variabili d'ambiente)
+type Config struct {
+	Node       NodeConfig       `yaml:"node"`
+	Gossip     GossipConfig     `yaml:"gossip"`
+	Failure    FailureConfig    `yaml:"failure"`
+	Join       JoinConfig       `yaml:"join"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+	Identity   IdentityConfig   `yaml:"identity"`
+	TLS        TLSConfig        `yaml:"tls"`
+	Security   SecurityConfig   `yaml:"security"`
+	Logging    LoggingConfig    `yaml:"logging"`
+}
+
+// ✅ Identità e indirizzo del nodo
+type NodeConfig struct {
+	ID       string   `yaml:"id"`        // Nome univoco e stabile del nodo (NODE_ID)
+	IP       string   `yaml:"ip"`        // Indirizzo annunciato agli altri nodi, vuoto = scoperto durante il JOIN (NODE_IP)
+	Port     string   `yaml:"port"`      // Porta annunciata agli altri nodi (NODE_PORT)
+	BindIP   string   `yaml:"bind_ip"`   // Indirizzo locale di ascolto, vuoto = tutte le interfacce (BIND_IP)
+	BindPort string   `yaml:"bind_port"` // Porta locale di ascolto, vuota = node.port (BIND_PORT)
+	Seeds    []string `yaml:"seeds"`     // Nodi "ip:port" da contattare per il bootstrap (SEED_NODES)
+}
+
+// ✅ Parametri del ciclo di gossip
+type GossipConfig struct {
+	Interval         time.Duration `yaml:"interval"`           // GOSSIP_INTERVAL
+	Fanout           int           `yaml:"fanout"`             // GOSSIP_FANOUT
+	PushPullInterval time.Duration `yaml:"push_pull_interval"` // PUSH_PULL_INTERVAL
+}
+
+// ✅ Parametri del failure detector e del Prober SWIM
+type FailureConfig struct {
+	Detector           string        `yaml:"detector"`            // FAILURE_DETECTOR: timeout | phi
+	PhiThreshold       float64       `yaml:"phi_threshold"`       // PHI_THRESHOLD
+	CheckInterval      time.Duration `yaml:"check_interval"`      // FAILURE_CHECK_INTERVAL
+	DeadTimeout        time.Duration `yaml:"dead_timeout"`        // DEAD_TIMEOUT
+	TombstoneRetention time.Duration `yaml:"tombstone_retention"` // TOMBSTONE_RETENTION
+	ProbeInterval      time.Duration `yaml:"probe_interval"`      // PROBE_INTERVAL
+	ProbeTimeout       time.Duration `yaml:"probe_timeout"`       // PROBE_TIMEOUT
+	IndirectChecks     int           `yaml:"indirect_checks"`     // INDIRECT_CHECKS
+}
+
+// ✅ Parametri del JOIN verso i seed
+type JoinConfig struct {
+	Timeout        time.Duration `yaml:"timeout"`         // JOIN_TIMEOUT
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // JOIN_INITIAL_BACKOFF
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // JOIN_MAX_BACKOFF
+}
+
+// ✅ Cifratura del traffico tra i nodi
+type EncryptionConfig struct {
+	Keys []string `yaml:"keys"` // ENCRYPTION_KEYS: chiavi AES in base64 (16, 24 o 32 byte), la prima è la primaria; vuoto = in chiaro
+}
+
+// ✅ Identità Ed25519 del nodo e CA fidate (firma delle entry della Membership List)
+type IdentityConfig struct {
+	Key         string   `yaml:"key"`          // IDENTITY_KEY: chiave privata Ed25519 in base64 (seed da 32 byte o chiave da 64); vuoto = entry non firmate
+	Certificate string   `yaml:"certificate"`  // IDENTITY_CERTIFICATE: firma della CA su node.id e chiave pubblica, in base64
+	TrustedKeys []string `yaml:"trusted_keys"` // TRUSTED_KEYS: chiavi pubbliche Ed25519 delle CA fidate, in base64
+}
+
+// ✅ TLS mutuo sugli stream TCP (file PEM, riletti alla ricezione di SIGHUP)
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"` // TLS_CERT_FILE: certificato del nodo (node.id tra i nomi DNS); vuoto = TLS disattivato
+	KeyFile  string `yaml:"key_file"`  // TLS_KEY_FILE: chiave privata del certificato
+	CAFile   string `yaml:"ca_file"`   // TLS_CA_FILE: CA che ha emesso i certificati dei nodi
+}
+
+// ✅ Autenticazione dei messaggi in assenza di keyring e identità
+type SecurityConfig struct {
+	InsecureSelfClaims bool `yaml:"insecure_self_claims"` // INSECURE_SELF_CLAIMS: accetta LEAVE e confutazioni non autenticati (solo reti fidate)
+}
+
+// ✅ Parametri di logging
+type LoggingConfig struct {
+	Level string `yaml:"level"` // LOG_LEVEL: debug | info | warn | error
+}
+
+// ✅ Configurazione di default (gli stessi valori usati dai singoli sottosistemi)
+func Default() Config {
+	defaults := memberlist.DefaultConfig()
+
+	return Config{
+		Gossip: GossipConfig{
+			Interval:         defaults.GossipInterval,
+			Fanout:           defaults.GossipFanout,
+			PushPullInterval: defaults.PushPullInterval,
+		},
+		Failure: FailureConfig{
+			Detector:           defaults.FailureDetector,
+			PhiThreshold:       defaults.PhiThreshold,
+			CheckInterval:      defaults.FailureCheckInterval,
+			DeadTimeout:        defaults.DeadTimeout,
+			TombstoneRetention: defaults.TombstoneRetention,
+			ProbeInterval:      defaults.ProbeInterval,
+			ProbeTimeout:       defaults.ProbeTimeout,
+			IndirectChecks:     defaults.IndirectChecks,
+		},
+		Join: JoinConfig{
+			Timeout:        defaults.JoinTimeout,
+			InitialBackoff: defaults.JoinInitialBackoff,
+			MaxBackoff:     defaults.JoinMaxBackoff,
+		},
+		Logging: LoggingConfig{
+			Level: "info",
+		},
+	}
+}
+
+// ✅ Carica la configurazione: default → file YAML → variabili d'ambiente, poi valida.
+// Se path è vuoto viene usato DefaultPath, che può anche non esistere.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("errore parsing %s: %v", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// Nessun file di default: si usano solo default e variabili d'ambiente
+	default:
+		return cfg, fmt.Errorf("errore lettura %s: %v", path, err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// ✅ Applica gli override dalle variabili d'ambiente (hanno precedenza sul file)
+func (cfg *Config) applyEnv() error {
+	setString := func(name string, target *string) {
+		if value := os.Getenv(name); value != "" {
+			*target = value
+		}
+	}
+	setDuration := func(name string, target *time.Duration) error {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s non valido: %s", name, value)
+			}
+			*target = parsed
+		}
+		return nil
+	}
+	setInt := func(name string, target *int) error {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s non valido: %s", name, value)
+			}
+			*target = parsed
+		}
+		return nil
+	}
+
+	setString("NODE_ID", &cfg.Node.ID)
+	setString("NODE_IP", &cfg.Node.IP)
+	setString("NODE_PORT", &cfg.Node.Port)
+	setString("BIND_IP", &cfg.Node.BindIP)
+	setString("BIND_PORT", &cfg.Node.BindPort)
+	if seeds := os.Getenv("SEED_NODES"); seeds != "" {
+		cfg.Node.Seeds = strings.Split(seeds, ",")
+	}
+
+	setString("FAILURE_DETECTOR", &cfg.Failure.Detector)
+	if value := os.Getenv("PHI_THRESHOLD"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("PHI_THRESHOLD non valido: %s", value)
+		}
+		cfg.Failure.PhiThreshold = parsed
+	}
+	setString("LOG_LEVEL", &cfg.Logging.Level)
+	if value := os.Getenv("INSECURE_SELF_CLAIMS"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("INSECURE_SELF_CLAIMS non valido: %s", value)
+		}
+		cfg.Security.InsecureSelfClaims = parsed
+	}
+	if keys := os.Getenv("ENCRYPTION_KEYS"); keys != "" {
+		cfg.Encryption.Keys = strings.Split(keys, ",")
+	}
+	setString("TLS_CERT_FILE", &cfg.TLS.CertFile)
+	setString("TLS_KEY_FILE", &cfg.TLS.KeyFile)
+	setString("TLS_CA_FILE", &cfg.TLS.CAFile)
+	setString("IDENTITY_KEY", &cfg.Identity.Key)
+	setString("IDENTITY_CERTIFICATE", &cfg.Identity.Certificate)
+	if keys := os.Getenv("TRUSTED_KEYS"); keys != "" {
+		cfg.Identity.TrustedKeys = strings.Split(keys, ",")
+	}
+
+	durations := []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"GOSSIP_INTERVAL", &cfg.Gossip.Interval},
+		{"PUSH_PULL_INTERVAL", &cfg.Gossip.PushPullInterval},
+		{"FAILURE_CHECK_INTERVAL", &cfg.Failure.CheckInterval},
+		{"DEAD_TIMEOUT", &cfg.Failure.DeadTimeout},
+		{"TOMBSTONE_RETENTION", &cfg.Failure.TombstoneRetention},
+		{"PROBE_INTERVAL", &cfg.Failure.ProbeInterval},
+		{"PROBE_TIMEOUT", &cfg.Failure.ProbeTimeout},
+		{"JOIN_TIMEOUT", &cfg.Join.Timeout},
+		{"JOIN_INITIAL_BACKOFF", &cfg.Join.InitialBackoff},
+		{"JOIN_MAX_BACKOFF", &cfg.Join.MaxBackoff},
+	}
+	for _, d := range durations {
+		if err := setDuration(d.name, d.target); err != nil {
+			return err
+		}
+	}
+
+	if err := setInt("GOSSIP_FANOUT", &cfg.Gossip.Fanout); err != nil {
+		return err
+	}
+	return setInt("INDIRECT_CHECKS", &cfg.Failure.IndirectChecks)
+}
+
+// ✅ Controlla che tutti i valori siano coerenti
+// Qui restano solo i controlli propri del file di configurazione (seed, decodifica di
+// chiavi e identità, livello di log): il resto è validato da memberlist.Config.Validate
+func (cfg Config) Validate() error {
+	// Senza seed nessuno può dirci con quale indirizzo siamo raggiungibili
+	if cfg.Node.IP == "" && len(cfg.Node.Seeds) == 0 {
+		return errors.New("node.ip (NODE_IP) è obbligatorio se non sono definiti seed")
+	}
+	for _, seed := range cfg.Node.Seeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("seed non valido (atteso host:port, IPv6 tra parentesi quadre): %s", seed)
+		}
+	}
+
+	if _, err := keyring.DecodeKeys(cfg.Encryption.Keys); err != nil {
+		return fmt.Errorf("encryption.keys non valide: %v", err)
+	}
+	if _, _, _, err := cfg.Identity.decode(); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(cfg.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("logging.level non valido: %s (valori ammessi: debug, info, warn, error)", cfg.Logging.Level)
+	}
+
+	if err := cfg.MemberlistConfig().Validate(); err != nil {
+		return fmt.Errorf("configurazione del nodo non valida: %v", err)
+	}
+	return nil
+}
+
+// ✅ Configurazione della libreria memberlist corrispondente (da chiamare dopo Validate)
+func (cfg Config) MemberlistConfig() memberlist.Config {
+	// Le chiavi sono già state verificate da Validate
+	encryptionKeys, _ := keyring.DecodeKeys(cfg.Encryption.Keys)
+	identityKey, certificate, trustedKeys, _ := cfg.Identity.decode()
+
+	return memberlist.Config{
+		Name:                 cfg.Node.ID,
+		IP:                   cfg.Node.IP,
+		DiscoverIP:           cfg.Node.IP == "",
+		Port:                 cfg.Node.Port,
+		BindIP:               cfg.Node.BindIP,
+		BindPort:             cfg.Node.BindPort,
+		GossipInterval:       cfg.Gossip.Interval,
+		GossipFanout:         cfg.Gossip.Fanout,
+		PushPullInterval:     cfg.Gossip.PushPullInterval,
+		FailureDetector:      cfg.Failure.Detector,
+		PhiThreshold:         cfg.Failure.PhiThreshold,
+		FailureCheckInterval: cfg.Failure.CheckInterval,
+		DeadTimeout:          cfg.Failure.DeadTimeout,
+		TombstoneRetention:   cfg.Failure.TombstoneRetention,
+		ProbeInterval:        cfg.Failure.ProbeInterval,
+		ProbeTimeout:         cfg.Failure.ProbeTimeout,
+		IndirectChecks:       cfg.Failure.IndirectChecks,
+		JoinTimeout:          cfg.Join.Timeout,
+		JoinInitialBackoff:   cfg.Join.InitialBackoff,
+		JoinMaxBackoff:       cfg.Join.MaxBackoff,
+		EncryptionKeys:       encryptionKeys,
+		IdentityKey:          identityKey,
+		IdentityCertificate:  certificate,
+		TrustedKeys:          trustedKeys,
+		TLSCertFile:          cfg.TLS.CertFile,
+		TLSKeyFile:           cfg.TLS.KeyFile,
+		TLSCAFile:            cfg.TLS.CAFile,
+		InsecureSelfClaims:   cfg.Security.InsecureSelfClaims,
+	}
+}
+
+// ✅ Decodifica chiave, certificato e CA fidate (tutti vuoti = identità disattivata)
+func (c IdentityConfig) decode() (ed25519.PrivateKey, []byte, []ed25519.PublicKey, error) {
+	if c.Key == "" && c.Certificate == "" && len(c.TrustedKeys) == 0 {
+		return nil, nil, nil, nil
+	}
+	if c.Key == "" || c.Certificate == "" || len(c.TrustedKeys) == 0 {
+		return nil, nil, nil, errors.New("identity.key, identity.certificate e identity.trusted_keys vanno specificati insieme")
+	}
+
+	rawKey, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("identity.key non è in base64: %v", err)
+	}
+	var privateKey ed25519.PrivateKey
+	switch len(rawKey) {
+	case ed25519.SeedSize:
+		privateKey = ed25519.NewKeyFromSeed(rawKey)
+	case ed25519.PrivateKeySize:
+		privateKey = ed25519.PrivateKey(rawKey)
+	default:
+		return nil, nil, nil, fmt.Errorf("identity.key di %d byte (attesi %d o %d)", len(rawKey), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+
+	certificate, err := base64.StdEncoding.DecodeString(c.Certificate)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("identity.certificate non è in base64: %v", err)
+	}
+
+	trustedKeys := make([]ed25519.PublicKey, 0, len(c.TrustedKeys))
+	for _, encoded := range c.TrustedKeys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, nil, nil, fmt.Errorf("identity.trusted_keys: chiave non valida %q (attesa chiave pubblica Ed25519 in base64)", encoded)
+		}
+		trustedKeys = append(trustedKeys, ed25519.PublicKey(key))
+	}
+	return privateKey, certificate, trustedKeys, nil
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Variabili d'ambiente lette da applyEnv
+var envNames = []string{
+	"NODE_ID", "NODE_IP", "NODE_PORT", "BIND_IP", "BIND_PORT", "SEED_NODES",
+	"GOSSIP_INTERVAL", "GOSSIP_FANOUT", "PUSH_PULL_INTERVAL",
+	"FAILURE_DETECTOR", "PHI_THRESHOLD", "FAILURE_CHECK_INTERVAL", "DEAD_TIMEOUT", "TOMBSTONE_RETENTION",
+	"PROBE_INTERVAL", "PROBE_TIMEOUT", "INDIRECT_CHECKS",
+	"JOIN_TIMEOUT", "JOIN_INITIAL_BACKOFF", "JOIN_MAX_BACKOFF",
+	"ENCRYPTION_KEYS", "IDENTITY_KEY", "IDENTITY_CERTIFICATE", "TRUSTED_KEYS",
+	"TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CA_FILE",
+	"INSECURE_SELF_CLAIMS", "LOG_LEVEL",
+}
+
+// ✅ Ambiente pulito (una variabile vuota equivale a non impostata) più le variabili in env
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range envNames {
+		t.Setenv(name, env[name])
+	}
+}
+
+// ✅ Scrive un file di configurazione temporaneo e ne restituisce il percorso
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("scrittura %s: %v", path, err)
+	}
+	return path
+}
+
+const testYAML = `
+node:
+  id: node1
+  ip: 10.0.0.1
+  port: "8001"
+  seeds: ["10.0.0.2:8002"]
+gossip:
+  interval: 2s
+  fanout: 2
+failure:
+  detector: phi
+  phi_threshold: 9
+security:
+  insecure_self_claims: true
+logging:
+  level: debug
+`
+
+func TestLoadYAML(t *testing.T) {
+	setEnv(t, nil)
+
+	cfg, err := Load(writeConfig(t, testYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Node.ID != "node1" || cfg.Node.IP != "10.0.0.1" || cfg.Node.Port != "8001" {
+		t.Errorf("nodo: %+v", cfg.Node)
+	}
+	if !slices.Equal(cfg.Node.Seeds, []string{"10.0.0.2:8002"}) {
+		t.Errorf("seed: %v", cfg.Node.Seeds)
+	}
+	if cfg.Gossip.Interval != 2*time.Second || cfg.Gossip.Fanout != 2 {
+		t.Errorf("gossip: %+v", cfg.Gossip)
+	}
+	if cfg.Failure.Detector != "phi" || cfg.Failure.PhiThreshold != 9 {
+		t.Errorf("failure detector: %s (soglia %v)", cfg.Failure.Detector, cfg.Failure.PhiThreshold)
+	}
+	if !cfg.Security.InsecureSelfClaims || cfg.Logging.Level != "debug" {
+		t.Errorf("security %+v, logging %+v", cfg.Security, cfg.Logging)
+	}
+
+	// I valori assenti dal file restano quelli di default
+	defaults := Default()
+	if cfg.Gossip.PushPullInterval != defaults.Gossip.PushPullInterval || cfg.Join != defaults.Join {
+		t.Errorf("default non applicati: push-pull %v, join %+v", cfg.Gossip.PushPullInterval, cfg.Join)
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	keys := []string{
+		base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+		base64.StdEncoding.EncodeToString([]byte("fedcba9876543210")),
+	}
+	setEnv(t, map[string]string{
+		"NODE_IP":              "10.0.0.9",
+		"SEED_NODES":           "10.0.0.2:8002,[fd00::3]:8003",
+		"GOSSIP_INTERVAL":      "250ms",
+		"GOSSIP_FANOUT":        "3",
+		"PHI_THRESHOLD":        "10.5",
+		"DEAD_TIMEOUT":         "2m",
+		"JOIN_MAX_BACKOFF":     "1s",
+		"INSECURE_SELF_CLAIMS": "false",
+		"ENCRYPTION_KEYS":      strings.Join(keys, ","),
+		"LOG_LEVEL":            "warn",
+	})
+
+	// Le variabili d'ambiente hanno precedenza sul file; il resto viene dal file
+	cfg, err := Load(writeConfig(t, testYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Node.ID != "node1" || cfg.Node.IP != "10.0.0.9" {
+		t.Errorf("nodo: %+v", cfg.Node)
+	}
+	if !slices.Equal(cfg.Node.Seeds, []string{"10.0.0.2:8002", "[fd00::3]:8003"}) {
+		t.Errorf("seed: %v", cfg.Node.Seeds)
+	}
+	if cfg.Gossip.Interval != 250*time.Millisecond || cfg.Gossip.Fanout != 3 {
+		t.Errorf("gossip: %+v", cfg.Gossip)
+	}
+	if cfg.Failure.Detector != "phi" || cfg.Failure.PhiThreshold != 10.5 || cfg.Failure.DeadTimeout != 2*time.Minute {
+		t.Errorf("failure: %+v", cfg.Failure)
+	}
+	if cfg.Join.MaxBackoff != time.Second {
+		t.Errorf("join: %+v", cfg.Join)
+	}
+	if cfg.Security.InsecureSelfClaims {
+		t.Error("INSECURE_SELF_CLAIMS=false non ha sovrascritto il file")
+	}
+	if !slices.Equal(cfg.Encryption.Keys, keys) || cfg.Logging.Level != "warn" {
+		t.Errorf("chiavi %v, logging %+v", cfg.Encryption.Keys, cfg.Logging)
+	}
+	if memberlistConfig := cfg.MemberlistConfig(); len(memberlistConfig.EncryptionKeys) != 2 || memberlistConfig.DiscoverIP {
+		t.Errorf("configurazione memberlist: %d chiavi, DiscoverIP %v", len(memberlistConfig.EncryptionKeys), memberlistConfig.DiscoverIP)
+	}
+}
+
+func TestLoadDefaultPath(t *testing.T) {
+	// Senza percorso esplicito il file di default può mancare: bastano le variabili d'ambiente
+	t.Chdir(t.TempDir())
+	setEnv(t, map[string]string{"NODE_ID": "node1", "NODE_PORT": "8001", "SEED_NODES": "10.0.0.2:8002"})
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Node.IP != "" || !cfg.MemberlistConfig().DiscoverIP {
+		t.Errorf("IP %q senza scoperta durante il JOIN", cfg.Node.IP)
+	}
+
+	if _, err := Load("manca.yml"); err == nil {
+		t.Error("Load di un file esplicito inesistente riuscito")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"YAML non valido", "node: [", nil, "errore parsing"},
+		{"durata non valida", testYAML, map[string]string{"PROBE_TIMEOUT": "mezzo secondo"}, "PROBE_TIMEOUT non valido"},
+		{"intero non valido", testYAML, map[string]string{"GOSSIP_FANOUT": "tre"}, "GOSSIP_FANOUT non valido"},
+		{"booleano non valido", testYAML, map[string]string{"INSECURE_SELF_CLAIMS": "forse"}, "INSECURE_SELF_CLAIMS non valido"},
+		{"decimale non valido", testYAML, map[string]string{"PHI_THRESHOLD": "otto"}, "PHI_THRESHOLD non valido"},
+		{"né IP né seed", "node: {id: node1, port: \"8001\"}", nil, "node.ip"},
+		{"seed senza porta", testYAML, map[string]string{"SEED_NODES": "10.0.0.2"}, "seed non valido"},
+		{"chiave non valida", testYAML, map[string]string{"ENCRYPTION_KEYS": "corta"}, "encryption.keys non valide"},
+		{"livello di log sconosciuto", testYAML, map[string]string{"LOG_LEVEL": "verbose"}, "logging.level non valido"},
+		{"IP annunciato non specificato", testYAML, map[string]string{"NODE_IP": "0.0.0.0"}, "IP annunciato non valido"},
+		{"detector sconosciuto", testYAML, map[string]string{"FAILURE_DETECTOR": "oracolo"}, "configurazione del nodo non valida"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			_, err := Load(writeConfig(t, tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("errore %v, atteso %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,28 +2,81 @@ package failure
 
 import (
 	"Gossip/internal/util"
-	"log"
+	"context"
+	"fmt"
 	"time"
 
+	"Gossip/internal/clock"
 	"Gossip/internal/membership"
 )
 
+// ✅ Tipi di failure detector selezionabili da configurazione
+const (
+	DetectorTimeout = "timeout" // Soglie fisse su LastSeen (default)
+	DetectorPhi     = "phi"     // Phi-accrual sugli intervalli di arrivo degli heartbeat
+)
+
+// ✅ Parametri del failure detector e del Prober SWIM (configurabili all'avvio del nodo)
+type Config struct {
+	Detector           string        // DetectorTimeout oppure DetectorPhi
+	PhiThreshold       float64       // phi oltre il quale il nodo diventa SUSPECT (DEAD oltre il doppio)
+	CheckInterval      time.Duration // Frequenza dei controlli del detector a soglie fisse
+	DeadTimeout        time.Duration // SUSPECT da più di così (senza confutazione) → DEAD
+	TombstoneRetention time.Duration // Durata dei tombstone DEAD/LEFT prima dell'eliminazione
+	ProbeInterval      time.Duration // Ogni quanto viene sondato un nodo
+	ProbeTimeout       time.Duration // Attesa massima dell'ACK diretto prima dei ping_req
+	IndirectChecks     int           // Numero di nodi a cui chiedere il probe indiretto
+	Clock              clock.Clock   // Orologio di ticker, timeout e controlli su LastSeen (clock.Real o virtuale)
+}
+
+// ✅ Configurazione di default del failure detector
+func DefaultConfig() Config {
+	return Config{
+		Detector:           DetectorTimeout,
+		PhiThreshold:       8,
+		CheckInterval:      10 * time.Second,
+		DeadTimeout:        60 * time.Second,
+		TombstoneRetention: 120 * time.Second,
+		ProbeInterval:      1 * time.Second,
+		ProbeTimeout:       500 * time.Millisecond,
+		IndirectChecks:     3,
+		Clock:              clock.Real,
+	}
+}
+
 // ✅ Avvia il Failure Detector che controlla periodicamente i nodi sospetti/morti
-func StartFailureDetector(localMembership *membership.MembershipList, selfNode util.NodeStatus) {
-	ticker := time.NewTicker(10 * time.Second) // ✅ Controllo ogni 10 secondi
+// (con il detector a soglie fisse i nodi diventano SUSPECT tramite il Prober, vedi probe.go)
+// Se phiDetector è nil viene usato il detector a soglie fisse.
+// I tombstone (DEAD/LEFT) vengono eliminati dopo config.TombstoneRetention.
+// Il detector termina quando ctx viene cancellato
+func StartFailureDetector(ctx context.Context, config Config, localMembership *membership.MembershipList, selfNode util.NodeStatus, phiDetector *PhiDetector) {
+	interval := config.CheckInterval
+	if phiDetector != nil {
+		interval = phiCheckInterval // Il phi-accrual ha bisogno di campionare più spesso
+	}
+	ticker := config.Clock.NewTicker(interval)
 	defer ticker.Stop()
 
-	log.Println("[FAILURE] Failure Detector avviato.")
+	util.Info("[FAILURE] Failure Detector avviato.")
 
 	for {
-		<-ticker.C
-		checkForFailedNodes(localMembership, selfNode)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if phiDetector != nil {
+			phiDetector.checkForFailedNodes(localMembership, selfNode, config.TombstoneRetention)
+		} else {
+			checkForFailedNodes(config, localMembership, selfNode)
+		}
 	}
 }
 
 // ✅ Controlla tutti i nodi e marca quelli sospetti/morti in base ai timeout
-func checkForFailedNodes(localMembership *membership.MembershipList, selfNode util.NodeStatus) {
-	now := time.Now()
+func checkForFailedNodes(config Config, localMembership *membership.MembershipList, selfNode util.NodeStatus) {
+	now := config.Clock.Now()
 	nodes := localMembership.GetCopy()
 
 	for _, node := range nodes {
@@ -32,33 +85,42 @@ func checkForFailedNodes(localMembership *membership.MembershipList, selfNode ut
 			continue
 		}
 
-		// Parse del timestamp LastSeen
-		lastSeen, err := time.Parse(time.RFC3339, node.LastSeen)
+		// ✅ LOGICA NORMALE (non aggressiva):
+		// Il passaggio ALIVE → SUSPECT è deciso dal Prober SWIM (ping + ping_req falliti).
+		// Il timeout verso DEAD parte dall'inizio del sospetto, non dall'ultimo contatto:
+		// un nodo sospettato quando il suo LastSeen è già vecchio ha comunque DeadTimeout per confutare
+		timeInStatus, err := sinceStatusChange(now, node)
 		if err != nil {
-			log.Printf("[FAILURE] Errore parsing timestamp per nodo %s: %v", node.ID, err)
+			util.Warn(fmt.Sprintf("[FAILURE] Errore parsing timestamp per nodo %s: %v", node.ID, err))
 			continue
 		}
 
-		timeSinceLastSeen := now.Sub(lastSeen)
-
-		// ✅ LOGICA NORMALE (non aggressiva):
-
-		// Se nodo ALIVE e non visto da 30 secondi → SUSPECT
-		if node.Status == "alive" && timeSinceLastSeen > 30*time.Second {
-			localMembership.MarkNodeSuspect(node.ID)
-			log.Printf("[FAILURE] Nodo %s marcato come SUSPECT (non visto da %v)", node.ID, timeSinceLastSeen)
-		}
-
-		// Se nodo SUSPECT e non visto da 60 secondi → DEAD
-		if node.Status == "suspect" && timeSinceLastSeen > 60*time.Second {
+		// Se nodo SUSPECT da più di DeadTimeout → DEAD
+		if node.Status == "suspect" && timeInStatus > config.DeadTimeout {
 			localMembership.MarkNodeDead(node.ID)
-			log.Printf("[FAILURE] Nodo %s marcato come DEAD (non visto da %v)", node.ID, timeSinceLastSeen)
+			util.Info(fmt.Sprintf("[FAILURE] Nodo %s marcato come DEAD (sospetto da %v)", node.ID, timeInStatus))
 		}
 
-		// Rimuovi nodi DEAD dopo 120 secondi (pulizia)
-		if node.Status == "dead" && timeSinceLastSeen > 120*time.Second {
-			localMembership.RemoveNode(node.ID)
-			log.Printf("[FAILURE] Nodo %s rimosso dalla Membership List (morto da %v)", node.ID, timeSinceLastSeen)
-		}
+		// Elimina i tombstone DEAD/LEFT scaduti (pulizia)
+		purgeIfExpired(localMembership, node, timeInStatus, config.TombstoneRetention)
+	}
+}
+
+// ✅ Tempo trascorso da quando il nodo è entrato nello stato corrente (StatusSince locale)
+func sinceStatusChange(now time.Time, node util.NodeStatus) (time.Duration, error) {
+	since, err := time.Parse(time.RFC3339Nano, node.StatusSince)
+	if err != nil {
+		return 0, err
+	}
+	return now.Sub(since), nil
+}
+
+// ✅ Elimina un tombstone (DEAD/LEFT) da più di tombstoneRetention (comune a entrambi i detector).
+// La durata si misura dal passaggio a DEAD/LEFT, così ogni tombstone resta il tempo necessario
+// a fermare le entry ALIVE obsolete ancora in circolazione
+func purgeIfExpired(localMembership *membership.MembershipList, node util.NodeStatus, timeInStatus, tombstoneRetention time.Duration) {
+	if membership.IsTombstone(node.Status) && timeInStatus > tombstoneRetention {
+		localMembership.RemoveNode(node.ID)
+		util.Info(fmt.Sprintf("[FAILURE] Tombstone %s (%s) rimosso dalla Membership List dopo %v", node.ID, node.Status, timeInStatus))
 	}
 }
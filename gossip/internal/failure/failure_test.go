@@ -0,0 +1,390 @@
+package failure
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/membership"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// ✅ Membership List con il nodo locale e un membro "peer", su orologio virtuale
+func newTestMembership() (*membership.MembershipList, util.NodeStatus, *clock.Virtual) {
+	clk := clock.NewVirtual(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	self := util.NodeStatus{ID: "self", IP: "127.0.0.1", Port: "9000", Status: "alive"}
+	ml := membership.NewMembershipList(self.ID, clk)
+	ml.AddOrUpdateNode(self)
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "peer", IP: "127.0.0.1", Port: "9001", Status: "alive"})
+	return ml, self, clk
+}
+
+func status(t *testing.T, ml *membership.MembershipList, id string) string {
+	t.Helper()
+	current, exists := ml.GetNodeStatus(id)
+	if !exists {
+		return ""
+	}
+	return current
+}
+
+func TestTimeoutDetectorLifecycle(t *testing.T) {
+	config := DefaultConfig()
+	ml, self, clk := newTestMembership()
+	config.Clock = clk
+
+	// Il Prober ha marcato peer come SUSPECT subito dopo l'ultimo contatto
+	ml.MarkNodeSuspect("peer")
+
+	clk.Advance(config.DeadTimeout - time.Second)
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "suspect" {
+		t.Fatalf("prima di DeadTimeout: stato %q, atteso suspect", got)
+	}
+
+	clk.Advance(2 * time.Second)
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "dead" {
+		t.Fatalf("dopo DeadTimeout: stato %q, atteso dead", got)
+	}
+
+	// Il tombstone resta per TombstoneRetention dal passaggio a DEAD, poi viene eliminato
+	clk.Advance(config.TombstoneRetention - time.Second)
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "dead" {
+		t.Fatalf("prima di TombstoneRetention: stato %q, atteso dead", got)
+	}
+
+	clk.Advance(2 * time.Second)
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "" {
+		t.Fatalf("dopo TombstoneRetention: stato %q, atteso rimosso", got)
+	}
+}
+
+func TestTimeoutDetectorStaleSuspect(t *testing.T) {
+	config := DefaultConfig()
+	ml, self, clk := newTestMembership()
+	config.Clock = clk
+
+	// Il LastSeen è già vecchio quando il nodo viene sospettato (es. un solo probe fallito dopo
+	// un lungo silenzio): il nodo ha comunque DeadTimeout per confutare
+	clk.Advance(3 * config.DeadTimeout)
+	ml.MarkNodeSuspect("peer")
+
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "suspect" {
+		t.Fatalf("appena sospettato: stato %q, atteso suspect", got)
+	}
+
+	clk.Advance(config.DeadTimeout - time.Second)
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "suspect" {
+		t.Fatalf("prima di DeadTimeout dal sospetto: stato %q, atteso suspect", got)
+	}
+
+	clk.Advance(2 * time.Second)
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "dead" {
+		t.Fatalf("dopo DeadTimeout dal sospetto: stato %q, atteso dead", got)
+	}
+
+	// Anche il tombstone dura TombstoneRetention dal passaggio a DEAD, non dall'ultimo contatto
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "dead" {
+		t.Fatalf("tombstone appena creato: stato %q, atteso dead", got)
+	}
+}
+
+func TestTimeoutDetectorSuspectNeedsRefutation(t *testing.T) {
+	config := DefaultConfig()
+	ml, self, clk := newTestMembership()
+	config.Clock = clk
+
+	// Ricevere messaggi dal nodo aggiorna solo LastSeen: il sospetto resta finché non viene confutato
+	ml.MarkNodeSuspect("peer")
+	clk.Advance(config.CheckInterval)
+	ml.UpdateLastSeen("peer")
+	checkForFailedNodes(config, ml, self)
+	if got := status(t, ml, "peer"); got != "suspect" {
+		t.Fatalf("nodo sentito ma non confutato: stato %q, atteso suspect", got)
+	}
+
+	// La confutazione (ALIVE con incarnation più alta) chiude il sospetto
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "peer", IP: "127.0.0.1", Port: "9001", Status: "alive", Incarnation: 1})
+	for elapsed := time.Duration(0); elapsed < 2*config.DeadTimeout; elapsed += config.CheckInterval {
+		clk.Advance(config.CheckInterval)
+		ml.UpdateLastSeen("peer") // Il nodo continua a rispondere
+		checkForFailedNodes(config, ml, self)
+	}
+	if got := status(t, ml, "peer"); got != "alive" {
+		t.Fatalf("nodo che ha confutato: stato %q, atteso alive", got)
+	}
+}
+
+func TestPhiDetectorLifecycle(t *testing.T) {
+	ml, self, clk := newTestMembership()
+	detector := NewPhiDetector(8, clk)
+	retention := DefaultConfig().TombstoneRetention
+
+	// Heartbeat regolari ogni secondo: phi resta basso
+	for i := 0; i < 60; i++ {
+		clk.Advance(time.Second)
+		ml.UpdateLastSeen("peer")
+		detector.checkForFailedNodes(ml, self, retention)
+	}
+	if got := status(t, ml, "peer"); got != "alive" {
+		t.Fatalf("con heartbeat regolari: stato %q, atteso alive", got)
+	}
+	if phi := detector.Phi("peer"); phi >= 1 {
+		t.Fatalf("phi con heartbeat regolari: %.2f", phi)
+	}
+
+	// Heartbeat interrotti: SUSPECT, poi DEAD, poi eliminazione del tombstone
+	var suspectAfter, deadAfter time.Duration
+	for elapsed := time.Second; elapsed <= retention+time.Minute; elapsed += time.Second {
+		clk.Advance(time.Second)
+		detector.checkForFailedNodes(ml, self, retention)
+
+		switch got := status(t, ml, "peer"); {
+		case got == "suspect" && suspectAfter == 0:
+			suspectAfter = elapsed
+		case got == "dead" && deadAfter == 0:
+			deadAfter = elapsed
+		case got == "":
+			if suspectAfter == 0 || deadAfter <= suspectAfter {
+				t.Fatalf("transizioni fuori ordine: suspect dopo %v, dead dopo %v", suspectAfter, deadAfter)
+			}
+			if elapsed <= retention {
+				t.Fatalf("tombstone eliminato dopo %v, prima di %v", elapsed, retention)
+			}
+			return
+		}
+	}
+	t.Fatalf("tombstone non eliminato (suspect dopo %v, dead dopo %v)", suspectAfter, deadAfter)
+}
+
+// ✅ Delegate che interroga il detector durante la notifica
+type phiQueryDelegate struct {
+	detector *PhiDetector
+	suspects []float64
+}
+
+func (d *phiQueryDelegate) NotifyJoin(util.NodeStatus)   {}
+func (d *phiQueryDelegate) NotifyUpdate(util.NodeStatus) {}
+func (d *phiQueryDelegate) NotifyLeave(util.NodeStatus)  {}
+func (d *phiQueryDelegate) NotifyDead(util.NodeStatus)   {}
+func (d *phiQueryDelegate) NotifySuspect(node util.NodeStatus) {
+	d.suspects = append(d.suspects, d.detector.Phi(node.ID))
+}
+
+func TestPhiDetectorNotifiesOutsideLock(t *testing.T) {
+	ml, self, clk := newTestMembership()
+	detector := NewPhiDetector(8, clk)
+	delegate := &phiQueryDelegate{detector: detector}
+	ml.SetEventDelegate(delegate)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 120 && len(delegate.suspects) == 0; i++ {
+			clk.Advance(time.Second)
+			detector.checkForFailedNodes(ml, self, time.Hour)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("il delegate è rimasto bloccato interrogando il detector")
+	}
+	if len(delegate.suspects) != 1 || delegate.suspects[0] < 8 {
+		t.Fatalf("notifiche SUSPECT: %v, attesa una con phi ≥ 8", delegate.suspects)
+	}
+}
+
+func TestPhiProbeFailureLeavesDecisionToDetector(t *testing.T) {
+	ml, self, clk := newTestMembership()
+	config := DefaultConfig()
+	config.Clock = clk
+	detector := NewPhiDetector(8, clk)
+
+	// Nessuno in ascolto all'indirizzo di peer: ping e ping_req vanno persi
+	nodeTransport, err := transport.NewMemoryNetwork().Listen(self.Address())
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer nodeTransport.Close()
+	prober := NewProber(config, nodeTransport, ml, self, detector)
+
+	peer, _ := ml.GetNode("peer")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		prober.probeNode(peer)
+	}()
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+			clk.Advance(10 * time.Millisecond)
+			runtime.Gosched()
+		}
+	}
+
+	if got := status(t, ml, "peer"); got != "alive" {
+		t.Fatalf("dopo un solo probe fallito con phi-accrual: stato %q, atteso alive", got)
+	}
+}
+
+func TestAckOnlyFromProbedNode(t *testing.T) {
+	ml, self, clk := newTestMembership()
+	config := DefaultConfig()
+	config.Clock = clk
+	nodeTransport, err := transport.NewMemoryNetwork().Listen(self.Address())
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer nodeTransport.Close()
+	prober := NewProber(config, nodeTransport, ml, self, nil)
+
+	seqNo, ackCh := prober.registerAck("peer", "")
+	defer prober.cancelAck(seqNo)
+	source := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9002}
+
+	// Un ACK con il numero di sequenza giusto ma da un altro mittente non conta
+	impostor := util.NodeStatus{ID: "impostor", IP: "127.0.0.1", Port: "9002", Status: "alive"}
+	prober.HandleProbeMessage(util.ProbeMessage{Type: "ack", SeqNo: seqNo, Sender: impostor}, source)
+	select {
+	case <-ackCh:
+		t.Fatal("ACK accettato da un nodo diverso da quello sondato")
+	default:
+	}
+
+	peer, _ := ml.GetNode("peer")
+	prober.HandleProbeMessage(util.ProbeMessage{Type: "ack", SeqNo: seqNo, Sender: peer}, source)
+	select {
+	case <-ackCh:
+	default:
+		t.Fatal("ACK del nodo sondato ignorato")
+	}
+}
+
+func TestPingReqOnlyForMembers(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   util.NodeStatus
+		wantPeer bool // ping inviato all'indirizzo di peer nella Membership List
+	}{
+		{"membro attivo", util.NodeStatus{ID: "peer", IP: "127.0.0.1", Port: "9001"}, true},
+		{"membro con indirizzo falsificato", util.NodeStatus{ID: "peer", IP: "127.0.0.1", Port: "9999"}, true},
+		{"nodo sconosciuto", util.NodeStatus{ID: "victim", IP: "127.0.0.1", Port: "9999"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ml, self, clk := newTestMembership()
+			config := DefaultConfig()
+			config.Clock = clk
+			network := transport.NewMemoryNetwork()
+			nodeTransport, _ := network.Listen(self.Address())
+			peer, _ := network.Listen("127.0.0.1:9001")
+			victim, _ := network.Listen("127.0.0.1:9999")
+			defer nodeTransport.Close()
+			defer peer.Close()
+			defer victim.Close()
+			prober := NewProber(config, nodeTransport, ml, self, nil)
+
+			requester := util.NodeStatus{ID: "requester", IP: "127.0.0.1", Port: "9003", Status: "alive"}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				prober.forwardProbe(util.ProbeMessage{Type: "ping_req", SeqNo: 1, Sender: requester, Target: tt.target})
+			}()
+			// Il ping parte prima dell'attesa dell'ACK: basta far scadere ProbeTimeout
+			for finished := false; !finished; {
+				select {
+				case <-done:
+					finished = true
+				default:
+					clk.Advance(10 * time.Millisecond)
+					runtime.Gosched()
+				}
+			}
+
+			if got := peer.Queued() > 0; got != tt.wantPeer {
+				t.Fatalf("ping a peer: %v, atteso %v", got, tt.wantPeer)
+			}
+			if victim.Queued() > 0 {
+				t.Fatal("ping inviato a un indirizzo scelto dal mittente del ping_req")
+			}
+		})
+	}
+}
+
+func TestPingRequiresSameNode(t *testing.T) {
+	old := util.NodeStatus{ID: "peer", IP: "127.0.0.1", Port: "9001", Status: "alive"}
+	tests := []struct {
+		name   string
+		sender util.NodeStatus // Chi risponde al ping sul vecchio indirizzo
+		want   bool
+	}{
+		{"stesso nodo", old, true},
+		{"altro nodo sul vecchio indirizzo", util.NodeStatus{ID: "other", IP: "127.0.0.1", Port: "9001", Status: "alive"}, false},
+		{"stesso nome con un altro indirizzo", util.NodeStatus{ID: "peer", IP: "127.0.0.1", Port: "9005", Status: "alive"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ml, self, clk := newTestMembership()
+			config := DefaultConfig()
+			config.Clock = clk
+			network := transport.NewMemoryNetwork()
+			nodeTransport, _ := network.Listen(self.Address())
+			target, _ := network.Listen(old.Address())
+			defer nodeTransport.Close()
+			defer target.Close()
+			prober := NewProber(config, nodeTransport, ml, self, nil)
+
+			result := make(chan bool, 1)
+			go func() { result <- prober.Ping(old) }()
+
+			// Risponde al ping ricevuto con il suo numero di sequenza
+			buffer := make([]byte, 64*1024)
+			n, _, err := target.ReadFrom(buffer)
+			if err != nil {
+				t.Fatalf("ReadFrom: %v", err)
+			}
+			var ping util.ProbeMessage
+			if err := json.Unmarshal(buffer[:n], &ping); err != nil {
+				t.Fatalf("ping non valido: %v", err)
+			}
+			source := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9001}
+			prober.HandleProbeMessage(util.ProbeMessage{Type: "ack", SeqNo: ping.SeqNo, Sender: tt.sender}, source)
+
+			for {
+				select {
+				case got := <-result:
+					if got != tt.want {
+						t.Fatalf("Ping = %v, atteso %v", got, tt.want)
+					}
+					return
+				default:
+					clk.Advance(10 * time.Millisecond)
+					runtime.Gosched()
+				}
+			}
+		})
+	}
+}
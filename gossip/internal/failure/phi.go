@@ -0,0 +1,211 @@
+package failure
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/membership"
+	"Gossip/internal/util"
+)
+
+const (
+	phiCheckInterval       = 1 * time.Second        // Frequenza di campionamento degli heartbeat
+	phiWindowSize          = 1000                   // Numero massimo di intervalli memorizzati per nodo
+	phiMinStdDev           = 500 * time.Millisecond // Deviazione standard minima (evita phi esplosivi con heartbeat regolari)
+	phiFirstHeartbeatGuess = 5 * time.Second        // Stima iniziale dell'intervallo tra heartbeat
+)
+
+// ✅ Storico degli arrivi di heartbeat di un singolo nodo
+type arrivalWindow struct {
+	lastArrival time.Time
+	intervals   []float64 // Intervalli tra arrivi successivi, in secondi
+}
+
+// ✅ PhiDetector implementa il failure detector phi-accrual (Hayashibara et al.)
+// Impara la distribuzione degli intervalli tra heartbeat di ciascun nodo e
+// calcola un livello di sospetto phi invece di usare soglie fisse
+type PhiDetector struct {
+	threshold float64     // phi oltre il quale il nodo diventa SUSPECT (DEAD oltre il doppio)
+	clock     clock.Clock // Orologio con cui confrontare gli arrivi
+
+	mutex   sync.Mutex
+	windows map[string]*arrivalWindow
+}
+
+// Costruttore: crea un PhiDetector con la soglia indicata (valori tipici 8-12)
+// e l'orologio dei timestamp LastSeen della Membership List
+func NewPhiDetector(threshold float64, clk clock.Clock) *PhiDetector {
+	return &PhiDetector{
+		threshold: threshold,
+		clock:     clk,
+		windows:   make(map[string]*arrivalWindow),
+	}
+}
+
+// ✅ Registra un nuovo arrivo se il LastSeen locale del nodo è avanzato
+// (LastSeen avanza con nuovi heartbeat, messaggi diretti e ACK dei probe; il chiamante deve possedere il lock)
+func (d *PhiDetector) observe(nodeID string, arrival time.Time) {
+	window, exists := d.windows[nodeID]
+	if !exists {
+		// Primo heartbeat: inizializza la finestra con una stima (media ± deviazione)
+		guess := phiFirstHeartbeatGuess.Seconds()
+		d.windows[nodeID] = &arrivalWindow{
+			lastArrival: arrival,
+			intervals:   []float64{guess - guess/4, guess + guess/4},
+		}
+		return
+	}
+
+	if !arrival.After(window.lastArrival) {
+		return
+	}
+
+	window.intervals = append(window.intervals, arrival.Sub(window.lastArrival).Seconds())
+	if len(window.intervals) > phiWindowSize {
+		window.intervals = window.intervals[1:]
+	}
+	window.lastArrival = arrival
+}
+
+// ✅ Restituisce il livello di sospetto phi corrente per un nodo (0 se sconosciuto)
+func (d *PhiDetector) Phi(nodeID string) float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.phiLocked(nodeID, d.clock.Now())
+}
+
+func (d *PhiDetector) phiLocked(nodeID string, now time.Time) float64 {
+	window, exists := d.windows[nodeID]
+	if !exists {
+		return 0
+	}
+
+	mean, stdDev := meanAndStdDev(window.intervals)
+	stdDev = math.Max(stdDev, phiMinStdDev.Seconds())
+	elapsed := now.Sub(window.lastArrival).Seconds()
+
+	// Approssimazione logistica della CDF normale (come in Akka/Cassandra)
+	y := (elapsed - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1.0 + e))
+	}
+	return -math.Log10(1.0 - 1.0/(1.0+e))
+}
+
+// ✅ Registra l'esito positivo di un probe (ACK diretto o indiretto): il LastSeen
+// aggiornato dall'ACK diventa subito un arrivo, senza attendere il prossimo campionamento.
+// Un probe fallito non aggiunge arrivi: phi cresce da solo con il tempo trascorso
+func (d *PhiDetector) ObserveNode(node util.NodeStatus) {
+	lastSeen, err := time.Parse(time.RFC3339Nano, node.LastSeen)
+	if err != nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.observe(node.ID, lastSeen)
+}
+
+// ✅ Transizione decisa dal detector, applicata dopo aver rilasciato il lock
+type phiTransition struct {
+	node   util.NodeStatus
+	status string // "suspect" o "dead"
+	phi    float64
+}
+
+// ✅ Campiona la Membership List e marca i nodi in base al livello phi.
+// Le transizioni vengono applicate fuori dal lock del detector: i Mark* notificano
+// l'EventDelegate, che può così interrogare il detector senza bloccarsi
+func (d *PhiDetector) checkForFailedNodes(localMembership *membership.MembershipList, selfNode util.NodeStatus, tombstoneRetention time.Duration) {
+	now := d.clock.Now()
+	nodes := localMembership.GetCopy()
+	transitions := d.evaluate(nodes, selfNode, now)
+
+	for _, transition := range transitions {
+		// phi ≥ soglia → SUSPECT, phi ≥ 2×soglia → DEAD
+		switch transition.status {
+		case "suspect":
+			localMembership.MarkNodeSuspect(transition.node.ID)
+			util.Info(fmt.Sprintf("[FAILURE] Nodo %s marcato come SUSPECT (phi=%.2f)", transition.node.ID, transition.phi))
+		case "dead":
+			localMembership.MarkNodeDead(transition.node.ID)
+			util.Info(fmt.Sprintf("[FAILURE] Nodo %s marcato come DEAD (phi=%.2f)", transition.node.ID, transition.phi))
+		}
+	}
+
+	for _, node := range nodes {
+		if node.ID == selfNode.ID {
+			continue
+		}
+		if timeInStatus, err := sinceStatusChange(now, node); err == nil {
+			purgeIfExpired(localMembership, node, timeInStatus, tombstoneRetention)
+		}
+	}
+}
+
+// ✅ Aggiorna lo storico con i LastSeen campionati e restituisce le transizioni da applicare
+func (d *PhiDetector) evaluate(nodes []util.NodeStatus, selfNode util.NodeStatus, now time.Time) []phiTransition {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	transitions := []phiTransition{}
+	present := make(map[string]bool, len(nodes))
+
+	for _, node := range nodes {
+		// ✅ SKIP del proprio nodo
+		if node.ID == selfNode.ID {
+			continue
+		}
+		present[node.ID] = true
+
+		lastSeen, err := time.Parse(time.RFC3339Nano, node.LastSeen)
+		if err != nil {
+			util.Warn(fmt.Sprintf("[FAILURE] Errore parsing timestamp per nodo %s: %v", node.ID, err))
+			continue
+		}
+
+		d.observe(node.ID, lastSeen)
+		phi := d.phiLocked(node.ID, now)
+
+		if node.Status == "alive" && phi >= d.threshold {
+			transitions = append(transitions, phiTransition{node: node, status: "suspect", phi: phi})
+		}
+		if node.Status == "suspect" && phi >= 2*d.threshold {
+			transitions = append(transitions, phiTransition{node: node, status: "dead", phi: phi})
+		}
+	}
+
+	// Dimentica lo storico dei nodi non più presenti
+	for nodeID := range d.windows {
+		if !present[nodeID] {
+			delete(d.windows, nodeID)
+		}
+	}
+	return transitions
+}
+
+// ✅ Media e deviazione standard degli intervalli (in secondi)
+func meanAndStdDev(intervals []float64) (float64, float64) {
+	if len(intervals) == 0 {
+		return 0, 0
+	}
+
+	sum := 0.0
+	for _, interval := range intervals {
+		sum += interval
+	}
+	mean := sum / float64(len(intervals))
+
+	variance := 0.0
+	for _, interval := range intervals {
+		variance += (interval - mean) * (interval - mean)
+	}
+	variance /= float64(len(intervals))
+
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,344 @@
+package failure
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+
+	"Gossip/internal/membership"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// ✅ Prober implementa il protocollo di probe SWIM (ping diretto + ping_req indiretto)
+type Prober struct {
+	config          Config
+	transport       transport.Transport
+	localMembership *membership.MembershipList
+	selfNode        util.NodeStatus
+	phiDetector     *PhiDetector // nil con il detector a soglie fisse
+
+	mutex      sync.Mutex
+	pending    map[uint64]*ackWait // ACK attesi per numero di sequenza (casuale, non indovinabile)
+	probeList  []string            // Ordine casuale (round-robin) dei nodi da sondare
+	probeIndex int
+}
+
+// Costruttore: crea un nuovo Prober per il nodo locale. Con il phi-accrual (phiDetector
+// non nil) gli esiti dei probe alimentano il detector, che decide da solo i SUSPECT
+func NewProber(config Config, nodeTransport transport.Transport, localMembership *membership.MembershipList, selfNode util.NodeStatus, phiDetector *PhiDetector) *Prober {
+	return &Prober{
+		config:          config,
+		transport:       nodeTransport,
+		localMembership: localMembership,
+		selfNode:        selfNode,
+		phiDetector:     phiDetector,
+		pending:         make(map[uint64]*ackWait),
+	}
+}
+
+// ✅ ACK atteso: il canale viene chiuso alla ricezione di un ACK con il numero di sequenza
+// giusto da uno dei mittenti ammessi (il nodo sondato, oppure i membri a cui è stato
+// chiesto un ping_req)
+type ackWait struct {
+	ch      chan struct{}
+	senders map[string]bool
+	address string // Se non vuoto, indirizzo annunciato atteso nell'ACK (vedi Ping)
+}
+
+// ✅ Avvia il ciclo di probe: ad ogni periodo sonda un membro (termina quando ctx viene cancellato)
+func (p *Prober) Start(ctx context.Context) {
+	ticker := p.config.Clock.NewTicker(p.config.ProbeInterval)
+	defer ticker.Stop()
+
+	util.Info("[PROBE] Prober SWIM avviato.")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Dopo il LEAVE il nodo non sonda più nessuno
+		if p.localMembership.HasLeft() {
+			continue
+		}
+
+		target, ok := p.nextTarget()
+		if !ok {
+			continue
+		}
+		p.probeNode(target)
+	}
+}
+
+// ✅ Sceglie il prossimo nodo da sondare (round-robin su una permutazione casuale)
+func (p *Prober) nextTarget() (util.NodeStatus, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Al termine del giro ricostruisce e rimescola la lista
+	if p.probeIndex >= len(p.probeList) {
+		p.probeList = p.probeList[:0]
+		for _, node := range p.localMembership.GetCopy() {
+			if node.ID != p.selfNode.ID && !membership.IsTombstone(node.Status) {
+				p.probeList = append(p.probeList, node.ID)
+			}
+		}
+		rand.Shuffle(len(p.probeList), func(i, j int) {
+			p.probeList[i], p.probeList[j] = p.probeList[j], p.probeList[i]
+		})
+		p.probeIndex = 0
+	}
+
+	// Salta i nodi rimossi, morti o usciti nel frattempo
+	for p.probeIndex < len(p.probeList) {
+		nodeID := p.probeList[p.probeIndex]
+		p.probeIndex++
+
+		if node, exists := p.localMembership.GetNode(nodeID); exists && !membership.IsTombstone(node.Status) {
+			return node, true
+		}
+	}
+	return util.NodeStatus{}, false
+}
+
+// ✅ Sonda un nodo: ping diretto, poi ping_req indiretti, infine SUSPECT
+// (con il phi-accrual il SUSPECT è deciso dal detector in base agli arrivi registrati)
+func (p *Prober) probeNode(target util.NodeStatus) {
+	seqNo, ackCh := p.registerAck(target.ID, "")
+	defer p.cancelAck(seqNo)
+
+	ping := util.ProbeMessage{
+		Type:   "ping",
+		SeqNo:  seqNo,
+		Sender: p.localMembership.Self(),
+	}
+	p.sendProbeMessage(target, ping)
+
+	select {
+	case <-ackCh:
+		p.ackReceived(target.ID)
+		return
+	case <-p.config.Clock.After(p.config.ProbeTimeout):
+	}
+
+	// ✅ Nessun ACK diretto: chiede a k altri membri di sondare il nodo per nostro conto
+	helpers := p.randomHelpers(target.ID)
+	p.allowAckFrom(seqNo, helpers)
+	pingReq := util.ProbeMessage{
+		Type:   "ping_req",
+		SeqNo:  seqNo,
+		Sender: p.localMembership.Self(),
+		Target: target,
+	}
+	for _, helper := range helpers {
+		p.sendProbeMessage(helper, pingReq)
+	}
+	util.Debug(fmt.Sprintf("[PROBE] Nessun ACK da %s, inviati %d ping_req indiretti", target.ID, len(helpers)))
+
+	select {
+	case <-ackCh:
+		p.ackReceived(target.ID)
+		util.Debug(fmt.Sprintf("[PROBE] ACK indiretto ricevuto per %s", target.ID))
+	case <-p.config.Clock.After(p.config.ProbeInterval - p.config.ProbeTimeout):
+		if p.phiDetector != nil {
+			util.Debug(fmt.Sprintf("[PROBE] Nessun ACK da %s: decide il phi-accrual", target.ID))
+			return
+		}
+		if status, exists := p.localMembership.GetNodeStatus(target.ID); exists && status == "alive" {
+			p.localMembership.MarkNodeSuspect(target.ID)
+			util.Info(fmt.Sprintf("[PROBE] Nodo %s marcato come SUSPECT (nessun ACK diretto né indiretto)", target.ID))
+		}
+	}
+}
+
+// ✅ ACK ricevuto per un probe: aggiorna LastSeen e lo registra come arrivo nel phi-accrual
+func (p *Prober) ackReceived(nodeID string) {
+	p.localMembership.UpdateLastSeen(nodeID)
+	if p.phiDetector == nil {
+		return
+	}
+	if node, exists := p.localMembership.GetNode(nodeID); exists {
+		p.phiDetector.ObserveNode(node)
+	}
+}
+
+// ✅ Ping diretto sincrono: true se entro ProbeTimeout risponde proprio target, cioè un ACK
+// con il suo ID e ancora il suo indirizzo (un altro nodo che ora usa quell'indirizzo non conta)
+func (p *Prober) Ping(target util.NodeStatus) bool {
+	seqNo, ackCh := p.registerAck(target.ID, target.Address())
+	defer p.cancelAck(seqNo)
+
+	ping := util.ProbeMessage{
+		Type:   "ping",
+		SeqNo:  seqNo,
+		Sender: p.localMembership.Self(),
+	}
+	p.sendProbeMessage(target, ping)
+
+	select {
+	case <-ackCh:
+		return true
+	case <-p.config.Clock.After(p.config.ProbeTimeout):
+		return false
+	}
+}
+
+// ✅ Seleziona fino a IndirectChecks membri attivi (escludendo sé stesso e il target)
+func (p *Prober) randomHelpers(targetID string) []util.NodeStatus {
+	candidates := []util.NodeStatus{}
+	for _, node := range p.localMembership.GetCopy() {
+		if node.ID != p.selfNode.ID && node.ID != targetID && node.Status == "alive" {
+			candidates = append(candidates, node)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > p.config.IndirectChecks {
+		candidates = candidates[:p.config.IndirectChecks]
+	}
+	return candidates
+}
+
+// ✅ Gestisce un messaggio di probe ricevuto dal server UDP
+// (senderAddr è la sorgente effettiva del pacchetto)
+func (p *Prober) HandleProbeMessage(message util.ProbeMessage, senderAddr net.Addr) {
+	// ✅ Applica i rumour ricevuti in piggyback
+	if len(message.Rumours) > 0 {
+		p.localMembership.MergeMembership(message.Rumours)
+	}
+
+	// ✅ Un seed che risponde (o ci sonda) è verificato: entra nella Membership List
+	if message.Type == "ping" || message.Type == "ack" {
+		p.localMembership.PromoteBootstrapNode(message.Sender, senderAddr.String())
+	}
+
+	switch message.Type {
+	case "ping":
+		// Rispondi con ACK all'indirizzo di ascolto del mittente
+		ack := util.ProbeMessage{
+			Type:   "ack",
+			SeqNo:  message.SeqNo,
+			Sender: p.localMembership.Self(),
+		}
+		p.sendProbeMessage(message.Sender, ack)
+
+	case "ping_req":
+		go p.forwardProbe(message)
+
+	case "ack":
+		p.mutex.Lock()
+		if wait, exists := p.pending[message.SeqNo]; exists && wait.accepts(message.Sender) {
+			close(wait.ch)
+			delete(p.pending, message.SeqNo)
+		}
+		p.mutex.Unlock()
+
+	default:
+		util.Warn(fmt.Sprintf("[PROBE] Tipo messaggio di probe sconosciuto: %s", message.Type))
+	}
+}
+
+// ✅ Esegue un ping per conto di un altro nodo e gli inoltra l'eventuale ACK.
+// Il target deve essere un membro attivo e viene sondato all'indirizzo della Membership List:
+// un ping_req falsificato non può far inviare pacchetti a un host arbitrario
+func (p *Prober) forwardProbe(request util.ProbeMessage) {
+	target, exists := p.localMembership.GetNode(request.Target.ID)
+	if !exists || membership.IsTombstone(target.Status) || target.ID == p.selfNode.ID {
+		util.Debug(fmt.Sprintf("[PROBE] ping_req da %s ignorato: %s non è un membro attivo", request.Sender.ID, request.Target.ID))
+		return
+	}
+
+	seqNo, ackCh := p.registerAck(target.ID, "")
+	defer p.cancelAck(seqNo)
+
+	ping := util.ProbeMessage{
+		Type:   "ping",
+		SeqNo:  seqNo,
+		Sender: p.localMembership.Self(),
+	}
+	p.sendProbeMessage(target, ping)
+
+	select {
+	case <-ackCh:
+		// L'ACK inoltrato usa il numero di sequenza originale del richiedente
+		ack := util.ProbeMessage{
+			Type:   "ack",
+			SeqNo:  request.SeqNo,
+			Sender: p.localMembership.Self(),
+		}
+		p.sendProbeMessage(request.Sender, ack)
+	case <-p.config.Clock.After(p.config.ProbeTimeout):
+	}
+}
+
+// ✅ Registra un nuovo numero di sequenza casuale in attesa dell'ACK di from
+// (con address non vuoto, solo se from annuncia ancora quell'indirizzo)
+func (p *Prober) registerAck(from, address string) (uint64, chan struct{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	seqNo := randomSeqNo()
+	for p.pending[seqNo] != nil {
+		seqNo = randomSeqNo()
+	}
+	wait := &ackWait{ch: make(chan struct{}), senders: map[string]bool{from: true}, address: address}
+	p.pending[seqNo] = wait
+	return seqNo, wait.ch
+}
+
+// ✅ Accetta l'ACK di seqNo anche dai membri che lo inoltrano per un ping_req
+func (p *Prober) allowAckFrom(seqNo uint64, helpers []util.NodeStatus) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if wait, exists := p.pending[seqNo]; exists {
+		for _, helper := range helpers {
+			wait.senders[helper.ID] = true
+		}
+	}
+}
+
+func (w *ackWait) accepts(sender util.NodeStatus) bool {
+	return w.senders[sender.ID] && (w.address == "" || sender.Address() == w.address)
+}
+
+func randomSeqNo() uint64 {
+	var buffer [8]byte
+	cryptorand.Read(buffer[:])
+	return binary.BigEndian.Uint64(buffer[:])
+}
+
+// ✅ Rimuove un numero di sequenza non più atteso
+func (p *Prober) cancelAck(seqNo uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.pending, seqNo)
+}
+
+// ✅ Invia un messaggio di probe all'indirizzo di ascolto di un nodo,
+// allegando in piggyback i rumour in attesa di diffusione
+func (p *Prober) sendProbeMessage(target util.NodeStatus, message util.ProbeMessage) {
+	addr := target.Address()
+	message.Rumours = p.localMembership.GetRumours(membership.MaxPiggybackRumours)
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		util.Error(fmt.Sprintf("[PROBE] Errore serializzazione messaggio %s: %v", message.Type, err))
+		return
+	}
+
+	err = p.transport.SendTo(addr, data)
+	if err != nil {
+		util.Debug(fmt.Sprintf("[PROBE] Errore invio %s a %s: %v", message.Type, addr, err))
+	}
+}
@@ -0,0 +1,99 @@
+package gossip
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"Gossip/internal/membership"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// ✅ SenderVerifier autentica il mittente dichiarato dai messaggi ricevuti.
+// L'indirizzo sorgente dei pacchetti NON è un'identità (cambia dietro NAT o con una BindPort
+// diversa dalla porta annunciata e può essere falsificato): l'autenticazione dipende dalla
+// crittografia configurata sul nodo:
+//   - identità attive: la entry del mittente deve portare una firma valida (vedi internal/identity),
+//     quindi solo il nodo stesso può dichiarare il proprio LEAVE o la propria confutazione ALIVE;
+//   - solo keyring: ogni pacchetto decifrato arriva da chi conosce la chiave del cluster, e il
+//     mittente dichiarato è attendibile quanto i membri stessi;
+//   - né keyring né identità: i messaggi non sono autenticati. Le dichiarazioni di un nodo su
+//     sé stesso (LEAVE, confutazione ALIVE) vengono rifiutate, salvo che l'operatore abbia scelto
+//     la modalità insicura (memberlist.Config.InsecureSelfClaims); rumour e push-pull restano
+//     esposti a chiunque raggiunga la porta.
+//
+// I messaggi rifiutati vengono registrati nei log e contati per tipo
+type SenderVerifier struct {
+	localMembership *membership.MembershipList
+
+	mutex    sync.Mutex
+	rejected map[string]uint64 // Tipo di messaggio → messaggi rifiutati
+}
+
+// Costruttore: crea un SenderVerifier per la Membership List locale
+func NewSenderVerifier(localMembership *membership.MembershipList) *SenderVerifier {
+	return &SenderVerifier{
+		localMembership: localMembership,
+		rejected:        make(map[string]uint64),
+	}
+}
+
+// ✅ true se la entry sender con cui il messaggio si presenta è autentica (firma valida con
+// le identità attive). source è la provenienza del messaggio "ip:port", usata solo nei log
+func (v *SenderVerifier) Verify(messageType string, sender util.NodeStatus, source string) bool {
+	if sender.ID == "" {
+		v.Reject(messageType, "mittente non dichiarato (da %s)", source)
+		return false
+	}
+	if err := v.localMembership.VerifyEntry(sender); err != nil {
+		v.Reject(messageType, "mittente %s non autentico (da %s): %v", sender.ID, source, err)
+		return false
+	}
+	return true
+}
+
+// ✅ Come Verify, per i messaggi con cui il mittente dichiara qualcosa di sé (LEAVE,
+// confutazione ALIVE): senza identità né keyring il mittente non è autenticabile e il
+// messaggio viene rifiutato (vedi MembershipList.SelfClaimsAuthenticated)
+func (v *SenderVerifier) VerifySelfClaim(messageType string, sender util.NodeStatus, source string) bool {
+	if !v.Verify(messageType, sender, source) {
+		return false
+	}
+	if !v.localMembership.SelfClaimsAuthenticated() {
+		v.Reject(messageType, "mittente %s non autenticabile senza keyring né identità (da %s)", sender.ID, source)
+		return false
+	}
+	return true
+}
+
+// ✅ true se il certificato TLS del client dello stream è intestato al nodo name dichiarato
+// nel primo frame (sempre true se gli stream non usano il TLS mutuo)
+func (v *SenderVerifier) VerifyStreamPeer(messageType string, conn net.Conn, name string) bool {
+	if err := transport.VerifyPeerName(conn, name); err != nil {
+		v.Reject(messageType, "certificato TLS di %s non intestato a %s: %v", conn.RemoteAddr(), name, err)
+		return false
+	}
+	return true
+}
+
+// ✅ Registra nei log e conta un messaggio rifiutato
+func (v *SenderVerifier) Reject(messageType, format string, args ...any) {
+	v.mutex.Lock()
+	v.rejected[messageType]++
+	v.mutex.Unlock()
+
+	util.Warn(fmt.Sprintf("[GOSSIP] %s rifiutato: %s", messageType, fmt.Sprintf(format, args...)))
+}
+
+// ✅ Copia dei contatori dei messaggi rifiutati, per tipo
+func (v *SenderVerifier) Rejected() map[string]uint64 {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	rejected := make(map[string]uint64, len(v.rejected))
+	for messageType, count := range v.rejected {
+		rejected[messageType] = count
+	}
+	return rejected
+}
@@ -1,33 +1,46 @@
 package gossip
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"math/rand"
 	"net"
 	"time"
 
-	"Gossip/internal/join"
+	"Gossip/internal/clock"
+	"Gossip/internal/failure"
+	"Gossip/internal/keyring"
 	"Gossip/internal/membership"
+	"Gossip/internal/transport"
 	"Gossip/internal/util"
 )
 
-// ✅ Avvia il server UDP per ricevere messaggi (Gossip, JOIN, LEAVE)
-func StartUDPServer(port string, localMembership *membership.MembershipList, selfNode util.NodeStatus) {
-	addr := ":" + port
-	conn, err := net.ListenPacket("udp", addr)
-	if err != nil {
-		log.Fatalf("[GOSSIP] Errore avvio server UDP: %v", err)
-	}
-	defer conn.Close()
-	log.Printf("[GOSSIP] Server UDP in ascolto su %s\n", addr)
+// ✅ Avvia il server UDP per ricevere i messaggi piccoli (rumour, ALIVE, LEAVE, probe SWIM)
+// La porta viene aperta dal chiamante; il server termina quando viene chiusa.
+// Ogni messaggio (LEAVE, ALIVE, rumour, probe) viene applicato solo se verifier ne autentica
+// il mittente (vedi SenderVerifier).
+// Push-pull e JOIN viaggiano su TCP: vedi StartStreamServer
+func StartUDPServer(nodeTransport transport.Transport, localMembership *membership.MembershipList, selfNode util.NodeStatus, prober *failure.Prober, verifier *SenderVerifier) {
+	util.Info(fmt.Sprintf("[GOSSIP] Server UDP in ascolto su %s", nodeTransport.LocalAddr()))
 
-	buffer := make([]byte, 4096)
+	// Datagramma UDP massimo: con le identità attive ogni entry porta chiave, certificato e firma
+	buffer := make([]byte, 65535)
 
 	for {
-		n, senderAddr, err := conn.ReadFrom(buffer)
+		n, senderAddr, err := nodeTransport.ReadFrom(buffer)
 		if err != nil {
-			log.Printf("[GOSSIP] Errore ricezione messaggio: %v", err)
+			if errors.Is(err, net.ErrClosed) {
+				util.Info("[GOSSIP] Server UDP arrestato.")
+				return
+			}
+			if errors.Is(err, keyring.ErrUndecryptable) {
+				// ✅ Pacchetto in chiaro o cifrato con una chiave sconosciuta: non viene da un membro
+				verifier.Reject("undecryptable", "%v", err)
+				continue
+			}
+			util.Warn(fmt.Sprintf("[GOSSIP] Errore ricezione messaggio: %v", err))
 			continue
 		}
 
@@ -38,7 +51,7 @@ func StartUDPServer(port string, localMembership *membership.MembershipList, sel
 
 		err = json.Unmarshal(buffer[:n], &messageType)
 		if err != nil {
-			log.Printf("[GOSSIP] Messaggio non valido ricevuto: %v", err)
+			util.Warn(fmt.Sprintf("[GOSSIP] Messaggio non valido ricevuto: %v", err))
 			continue
 		}
 
@@ -49,174 +62,259 @@ func StartUDPServer(port string, localMembership *membership.MembershipList, sel
 			var leaveMsg util.LeaveMessage
 			err = json.Unmarshal(buffer[:n], &leaveMsg)
 			if err != nil {
-				log.Printf("[GOSSIP] Errore parsing LEAVE: %v", err)
+				util.Warn(fmt.Sprintf("[GOSSIP] Errore parsing LEAVE: %v", err))
 				continue
 			}
 
-			// Gestisci LEAVE direttamente qui
-			leavingNodeID := leaveMsg.Sender
-			log.Printf("[LEAVE] Ricevuto messaggio LEAVE da nodo %s", leavingNodeID)
-			localMembership.RemoveNode(leavingNodeID)
-			log.Printf("[LEAVE] Nodo %s rimosso dalla Membership List", leavingNodeID)
-
-		case "join":
-			// ✅ Gestione messaggio JOIN
-			go join.HandleJoinRequest(buffer[:n], senderAddr, localMembership, selfNode)
+			go HandleLeaveMessage(leaveMsg, senderAddr, localMembership, verifier)
 
-		case "gossip_update", "join_ack":
+		case "alive", "rumour":
 			// ✅ Gestione messaggi Gossip normali
 			var gossipMessage util.GossipMessage
 			err = json.Unmarshal(buffer[:n], &gossipMessage)
 			if err != nil {
-				log.Printf("[GOSSIP] Errore parsing Gossip message: %v", err)
+				util.Warn(fmt.Sprintf("[GOSSIP] Errore parsing Gossip message: %v", err))
+				continue
+			}
+			go HandleGossipMessage(gossipMessage, senderAddr, localMembership, verifier)
+
+		case "ping", "ping_req", "ack":
+			// ✅ Gestione messaggi di probe SWIM
+			var probeMessage util.ProbeMessage
+			err = json.Unmarshal(buffer[:n], &probeMessage)
+			if err != nil {
+				util.Warn(fmt.Sprintf("[GOSSIP] Errore parsing messaggio di probe: %v", err))
+				continue
+			}
+			// ✅ Anche i probe portano rumour in piggyback e promuovono i seed: mittente verificato
+			if !verifier.Verify(probeMessage.Type, probeMessage.Sender, senderAddr.String()) {
 				continue
 			}
-			go HandleGossipMessage(gossipMessage, senderAddr, localMembership, selfNode)
+			prober.HandleProbeMessage(probeMessage, senderAddr)
 
 		default:
-			log.Printf("[GOSSIP] Tipo messaggio sconosciuto: %s da %s", messageType.Type, senderAddr)
+			util.Warn(fmt.Sprintf("[GOSSIP] Tipo messaggio sconosciuto: %s da %s", messageType.Type, senderAddr))
 		}
 	}
 }
 
-func HandleLeaveMessage(leaveMsg util.LeaveMessage, senderAddr net.Addr, localMembership *membership.MembershipList) {
+// ✅ Gestisce un LEAVE: solo il nodo stesso può annunciare la propria uscita
+func HandleLeaveMessage(leaveMsg util.LeaveMessage, senderAddr net.Addr, localMembership *membership.MembershipList, verifier *SenderVerifier) {
 	leavingNodeID := leaveMsg.Sender
-	log.Printf("[LEAVE] Ricevuto messaggio LEAVE da nodo %s", leavingNodeID)
+	util.Debug(fmt.Sprintf("[LEAVE] Ricevuto messaggio LEAVE da nodo %s", leavingNodeID))
+	if leaveMsg.Node.ID != leavingNodeID || leaveMsg.Node.Status != "left" {
+		verifier.Reject("leave", "%s ha inviato la entry %s (%s)", leavingNodeID, leaveMsg.Node.ID, leaveMsg.Node.Status)
+		return
+	}
+	// ✅ Con le identità attive solo il nodo stesso può firmare la propria entry LEFT;
+	// senza identità né keyring il LEAVE non è autenticabile e viene rifiutato
+	if !verifier.VerifySelfClaim("leave", leaveMsg.Node, senderAddr.String()) {
+		return
+	}
 
-	// Rimuovi il nodo dalla Membership List
-	localMembership.RemoveNode(leavingNodeID)
-	log.Printf("[LEAVE] Nodo %s rimosso dalla Membership List", leavingNodeID)
+	// Applica la entry LEFT (tombstone) invece di rimuovere il nodo: con le identità
+	// attive è firmata dal nodo stesso e può quindi diffondersi come rumour
+	localMembership.AddOrUpdateNode(leaveMsg.Node)
+	if status, _ := localMembership.GetNodeStatus(leavingNodeID); status == "left" {
+		util.Info(fmt.Sprintf("[LEAVE] Nodo %s marcato come LEFT (tombstone)", leavingNodeID))
+	}
 }
 
-// ✅ Avvia il ciclo periodico di Gossip (Push-Pull + Heartbeat implicito)
-func StartGossipCycle(nodeID, nodeIP, nodePort string, localMembership *membership.MembershipList, selfNode util.NodeStatus) {
+// ✅ Parametri del ciclo di gossip (configurabili all'avvio del nodo)
+type Config struct {
+	Interval         time.Duration // Frequenza di diffusione dei rumour in piggyback
+	Fanout           int           // Numero di peer casuali contattati ad ogni round
+	PushPullInterval time.Duration // Frequenza della sincronizzazione completa (anti-entropy)
+	Clock            clock.Clock   // Orologio dei ticker (clock.Real o virtuale nel simulatore)
+}
 
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+// ✅ Configurazione di default del gossip
+func DefaultConfig() Config {
+	return Config{
+		Interval:         1 * time.Second,
+		Fanout:           1,
+		PushPullInterval: 30 * time.Second,
+		Clock:            clock.Real,
+	}
+}
 
-	for {
-		<-ticker.C
+// ✅ Avvia il ciclo periodico di Gossip:
+// - ogni config.Interval diffonde i rumour in attesa a config.Fanout peer
+// - ogni config.PushPullInterval esegue il Push-Pull completo (anti-entropy + heartbeat implicito)
+// Il ciclo termina quando ctx viene cancellato
+// verifier autentica il mittente delle risposte push-pull
+func StartGossipCycle(ctx context.Context, config Config, nodeTransport transport.Transport, localMembership *membership.MembershipList, selfNode util.NodeStatus, verifier *SenderVerifier) {
 
-		// ✅ AGGIORNA IL PROPRIO TIMESTAMP PRIMA DI TUTTO
-		localMembership.UpdateLastSeen(selfNode.ID)
+	gossipTicker := config.Clock.NewTicker(config.Interval)
+	defer gossipTicker.Stop()
+	pushPullTicker := config.Clock.NewTicker(config.PushPullInterval)
+	defer pushPullTicker.Stop()
 
-		// Seleziona peer casuali dalla Membership List (escludendo sé stesso e nodi morti)
-		peers := localMembership.GetCopy()
-		alivePeers := []util.NodeStatus{}
+	util.Info(fmt.Sprintf("[GOSSIP] Ciclo gossip avviato (intervallo %v, fanout %d, push-pull ogni %v)", config.Interval, config.Fanout, config.PushPullInterval))
 
-		// ✅ FILTRA: Solo nodi alive e suspect (esclude DEAD)
-		for _, peer := range peers {
-			if peer.ID != selfNode.ID && (peer.Status == "alive" || peer.Status == "suspect") {
-				alivePeers = append(alivePeers, peer)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gossipTicker.C:
+			// ✅ Dopo il LEAVE il nodo non diffonde più nulla: i tick restanti vengono ignorati
+			if !localMembership.HasLeft() {
+				gossipRumours(nodeTransport, localMembership, selfNode, config.Fanout)
 			}
+		case <-pushPullTicker.C:
+			if !localMembership.HasLeft() {
+				pushPull(config.Clock, nodeTransport, localMembership, selfNode, verifier)
+			}
+		case <-localMembership.Refutations():
+			// ✅ Il nodo locale è stato sospettato: annuncia subito la nuova incarnation
+			broadcastAlive(nodeTransport, localMembership, selfNode)
 		}
+	}
+}
 
-		// Se non ci sono peer disponibili, skip ciclo
-		if len(alivePeers) == 0 {
-			log.Println("[GOSSIP] Nessun peer disponibile per Gossip.")
-			continue
-		}
-
-		// ✅ Scelta realmente casuale del peer
-		target := alivePeers[rand.Intn(len(alivePeers))]
+// ✅ Invia i rumour in attesa a fanout peer casuali (nessun messaggio se la coda è vuota)
+func gossipRumours(nodeTransport transport.Transport, localMembership *membership.MembershipList, selfNode util.NodeStatus, fanout int) {
+	if localMembership.PendingRumours() == 0 {
+		return
+	}
 
-		// ✅ SMART MEMBERSHIP: Include solo nodi non DEAD nel gossip
-		allNodes := localMembership.GetCopy()
-		activeMembership := []util.NodeStatus{}
+	peers := alivePeers(localMembership, selfNode)
+	rand.Shuffle(len(peers), func(i, j int) {
+		peers[i], peers[j] = peers[j], peers[i]
+	})
+	if len(peers) > fanout {
+		peers = peers[:fanout]
+	}
 
-		for _, node := range allNodes {
-			// Include solo nodi che non sono DEAD
-			if node.Status != "dead" {
-				activeMembership = append(activeMembership, node)
-			}
+	for _, target := range peers {
+		// Ogni invio conta come una trasmissione per i rumour allegati
+		rumours := localMembership.GetRumours(membership.MaxPiggybackRumours)
+		if len(rumours) == 0 {
+			return
 		}
 
-		// ✅ Costruisce il Gossip Update con membership FILTRATA
 		message := util.GossipMessage{
-			Type:       "gossip_update",
-			Sender:     selfNode,
-			Membership: activeMembership, // Solo nodi attivi
+			Type:    "rumour",
+			Sender:  localMembership.Self(),
+			Rumours: rumours,
 		}
+		sendGossipMessage(nodeTransport, net.JoinHostPort(target.IP, target.Port), message)
 
-		// Invia Gossip Update al peer scelto
-		addr := net.JoinHostPort(target.IP, target.Port)
-		sendGossipMessage(addr, message)
-
-		log.Printf("[GOSSIP] Gossip Update inviato a %s con %d nodi", target.ID, len(activeMembership))
+		util.Debug(fmt.Sprintf("[GOSSIP] %d rumour inviati a %s", len(rumours), target.ID))
 	}
 }
 
-// ✅ Gestione del messaggio Gossip Update ricevuto
-// ✅ Gestione completa dei messaggi ricevuti (Gossip Update, JOIN, LEAVE)
-func HandleGossipMessage(message util.GossipMessage, senderAddr net.Addr, localMembership *membership.MembershipList, selfNode util.NodeStatus) {
+// ✅ Sincronizzazione completa Push-Pull con un peer casuale (percorso lento di anti-entropy)
+func pushPull(clk clock.Clock, nodeTransport transport.Transport, localMembership *membership.MembershipList, selfNode util.NodeStatus, verifier *SenderVerifier) {
+	// ✅ INCREMENTA IL PROPRIO HEARTBEAT PRIMA DI TUTTO
+	localMembership.IncrementHeartbeat(selfNode.ID)
 
-	// ✅ Gestione messaggio LEAVE
-	if message.Type == "leave" {
-		leavingNodeID := message.Sender.ID
-		log.Printf("[LEAVE] Ricevuto messaggio LEAVE da nodo %s", leavingNodeID)
+	peers := alivePeers(localMembership, selfNode)
 
-		// Rimuovi il nodo dalla Membership List
-		localMembership.RemoveNode(leavingNodeID)
-		log.Printf("[LEAVE] Nodo %s rimosso dalla Membership List", leavingNodeID)
+	// Se non ci sono peer disponibili, skip ciclo
+	if len(peers) == 0 {
+		util.Debug("[GOSSIP] Nessun peer disponibile per Gossip.")
 		return
 	}
 
-	// ✅ Gestione messaggio JOIN
-	if message.Type == "join" {
-		// Converti il messaggio a JoinMessage
-		joinData, _ := json.Marshal(message)
-		join.HandleJoinRequest(joinData, senderAddr, localMembership, selfNode)
-		return
+	// ✅ Scelta realmente casuale del peer
+	target := peers[rand.Intn(len(peers))]
+
+	// ✅ Include anche i nodi SUSPECT e DEAD: i rumour di sospetto/morte devono propagarsi
+	// (il nodo interessato può così confutarli incrementando la propria incarnation)
+	allNodes := localMembership.GetCopy()
+
+	// ✅ Costruisce il Gossip Update con l'intera membership
+	message := util.GossipMessage{
+		Type:       "gossip_update",
+		Sender:     localMembership.Self(),
+		Membership: allNodes,
 	}
 
-	// ✅ Gestione Gossip Update normale (tipo "gossip_update" o "join_ack")
-	if message.Type == "gossip_update" || message.Type == "join_ack" {
-		log.Printf("[GOSSIP] Ricevuto %s da %s con %d nodi.\n", message.Type, message.Sender.ID, len(message.Membership))
+	// Invia Gossip Update al peer scelto su TCP (fuori dal ciclo di gossip: può richiedere tempo)
+	go exchangeState(clk, nodeTransport, target, message, localMembership, verifier)
 
-		// Aggiorna la Membership List locale (merge)
-		for _, node := range message.Membership {
-			localMembership.AddOrUpdateNode(node)
+	util.Debug(fmt.Sprintf("[GOSSIP] Gossip Update inviato a %s con %d nodi", target.ID, len(allNodes)))
+}
+
+// ✅ Peer a cui inviare gossip: solo nodi alive e suspect, escluso sé stesso
+func alivePeers(localMembership *membership.MembershipList, selfNode util.NodeStatus) []util.NodeStatus {
+	peers := []util.NodeStatus{}
+	for _, peer := range localMembership.GetCopy() {
+		if peer.ID != selfNode.ID && (peer.Status == "alive" || peer.Status == "suspect") {
+			peers = append(peers, peer)
 		}
+	}
+	return peers
+}
+
+// ✅ Invia la propria entry ALIVE (con la nuova incarnation) a tutti i nodi raggiungibili
+func broadcastAlive(nodeTransport transport.Transport, localMembership *membership.MembershipList, selfNode util.NodeStatus) {
+	self, exists := localMembership.GetNode(selfNode.ID)
+	if !exists {
+		return
+	}
+
+	message := util.GossipMessage{
+		Type:       "alive",
+		Sender:     self,
+		Membership: []util.NodeStatus{self},
+	}
 
-		// Aggiorna anche l'ultimo visto del mittente (heartbeat implicito)
+	peers := alivePeers(localMembership, selfNode)
+	for _, peer := range peers {
+		sendGossipMessage(nodeTransport, net.JoinHostPort(peer.IP, peer.Port), message)
+	}
+
+	util.Info(fmt.Sprintf("[GOSSIP] Sospetto confutato: ALIVE con incarnation %d inviato a %d nodi", self.Incarnation, len(peers)))
+}
+
+// ✅ Gestione dei messaggi Gossip ricevuti su UDP (rumour, confutazione ALIVE)
+// Il mittente deve essere autentico (vedi SenderVerifier). I rumour possono riguardare altri
+// nodi: con le identità attive ogni entry ALIVE/LEFT deve portare la firma del nodo che
+// descrive, quindi un mittente non può dichiarare l'uscita o una nuova incarnation altrui
+func HandleGossipMessage(message util.GossipMessage, senderAddr net.Addr, localMembership *membership.MembershipList, verifier *SenderVerifier) {
+
+	// ✅ Gestione rumour in piggyback (solo cambiamenti di stato)
+	if message.Type == "rumour" {
+		if !verifier.Verify("rumour", message.Sender, senderAddr.String()) {
+			return
+		}
+		localMembership.MergeMembership(message.Rumours)
 		localMembership.UpdateLastSeen(message.Sender.ID)
+		return
+	}
 
-		// ✅ Fase di Pull: rispondi solo se è un gossip_update normale (non join_ack)
-		if message.Type == "gossip_update" {
-			myMembership := localMembership.GetCopy()
-			response := util.GossipMessage{
-				Type:       "gossip_update",
-				Sender:     selfNode,
-				Membership: myMembership,
+	// ✅ Confutazione "alive": stessa gestione di un Gossip Update, senza fase di Pull.
+	// Contiene solo la entry del mittente, che può essere dichiarata solo dal nodo stesso
+	if message.Type == "alive" {
+		if !verifier.VerifySelfClaim("alive", message.Sender, senderAddr.String()) {
+			return
+		}
+		for _, node := range message.Membership {
+			if node.ID != message.Sender.ID {
+				verifier.Reject("alive", "%s ha inviato la entry di %s", message.Sender.ID, node.ID)
+				return
 			}
-
-			// Serializza e invia risposta al mittente
-			sendGossipMessage(senderAddr.String(), response)
 		}
+		mergeGossipUpdate(message, localMembership)
 		return
 	}
 
 	// ✅ Messaggio non riconosciuto
-	log.Printf("[GOSSIP] Tipo messaggio non riconosciuto: %s", message.Type)
+	util.Warn(fmt.Sprintf("[GOSSIP] Tipo messaggio non riconosciuto: %s", message.Type))
 }
 
 // ✅ Funzione per inviare un messaggio Gossip a un peer
-func sendGossipMessage(addr string, message util.GossipMessage) {
-	conn, err := net.Dial("udp", addr)
-	if err != nil {
-		log.Printf("[GOSSIP] Errore connessione UDP a %s: %v", addr, err)
-		return
-	}
-	defer conn.Close()
-
+func sendGossipMessage(nodeTransport transport.Transport, addr string, message util.GossipMessage) {
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("[GOSSIP] Errore serializzazione messaggio: %v", err)
+		util.Error(fmt.Sprintf("[GOSSIP] Errore serializzazione messaggio: %v", err))
 		return
 	}
 
-	_, err = conn.Write(data)
+	err = nodeTransport.SendTo(addr, data)
 	if err != nil {
-		log.Printf("[GOSSIP] Errore invio messaggio Gossip a %s: %v", addr, err)
+		util.Debug(fmt.Sprintf("[GOSSIP] Errore invio messaggio Gossip a %s: %v", addr, err))
 	}
 }
@@ -0,0 +1,179 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/join"
+	"Gossip/internal/membership"
+	"Gossip/internal/rotation"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// Tempo massimo per uno scambio completo su TCP (push-pull o JOIN)
+const streamTimeout = 10 * time.Second
+
+// ✅ Avvia il server TCP per lo stato completo: Gossip Update (push-pull), JOIN e operazioni
+// sulle chiavi (keyManager è nil se il traffico non è cifrato). Il mittente dei Gossip Update
+// viene autenticato da verifier.
+// Ogni connessione trasporta un frame di richiesta e un frame di risposta;
+// il server termina quando la porta viene chiusa. Il timeout di ogni connessione è misurato su clk
+func StartStreamServer(clk clock.Clock, nodeTransport transport.Transport, localMembership *membership.MembershipList, joiner *join.Joiner, keyManager *rotation.Manager, verifier *SenderVerifier) {
+	util.Info(fmt.Sprintf("[GOSSIP] Server TCP in ascolto su %s", nodeTransport.LocalAddr()))
+
+	for {
+		conn, err := nodeTransport.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				util.Info("[GOSSIP] Server TCP arrestato.")
+				return
+			}
+			util.Warn(fmt.Sprintf("[GOSSIP] Errore connessione TCP: %v", err))
+			continue
+		}
+		go handleStream(clk, conn, localMembership, joiner, keyManager, verifier)
+	}
+}
+
+// ✅ Gestisce una singola connessione TCP: legge la richiesta e risponde sulla stessa connessione
+func handleStream(clk clock.Clock, conn net.Conn, localMembership *membership.MembershipList, joiner *join.Joiner, keyManager *rotation.Manager, verifier *SenderVerifier) {
+	defer conn.Close()
+	ctx, cancel := clock.WithTimeout(context.Background(), clk, streamTimeout)
+	defer cancel()
+	defer transport.Bind(ctx, conn)()
+
+	data, err := transport.ReadFrame(conn)
+	if err != nil {
+		util.Debug(fmt.Sprintf("[GOSSIP] Errore lettura da %s: %v", conn.RemoteAddr(), err))
+		return
+	}
+
+	// ✅ Prima determina il tipo di messaggio
+	var messageType struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &messageType); err != nil {
+		util.Warn(fmt.Sprintf("[GOSSIP] Messaggio non valido ricevuto su TCP: %v", err))
+		return
+	}
+
+	var response any
+	switch messageType.Type {
+	case "join":
+		// ✅ JOIN: la risposta (JOIN_ACK o rifiuto) contiene l'intera membership
+		var joinMsg util.JoinMessage
+		if err := json.Unmarshal(data, &joinMsg); err != nil {
+			util.Warn(fmt.Sprintf("[JOIN] Errore parsing JOIN ricevuto: %v", err))
+			return
+		}
+		if !verifier.VerifyStreamPeer("join", conn, joinMsg.Sender.ID) {
+			return
+		}
+		response = joiner.HandleJoinRequest(joinMsg, conn.RemoteAddr())
+
+	case "gossip_update":
+		// ✅ Push-pull: merge dello stato ricevuto, poi fase di Pull con il proprio stato
+		var gossipMessage util.GossipMessage
+		if err := json.Unmarshal(data, &gossipMessage); err != nil {
+			util.Warn(fmt.Sprintf("[GOSSIP] Errore parsing Gossip message: %v", err))
+			return
+		}
+		if !verifier.VerifyStreamPeer("gossip_update", conn, gossipMessage.Sender.ID) ||
+			!verifier.Verify("gossip_update", gossipMessage.Sender, conn.RemoteAddr().String()) {
+			return
+		}
+		mergeGossipUpdate(gossipMessage, localMembership)
+		response = util.GossipMessage{
+			Type:       "gossip_update",
+			Sender:     localMembership.Self(),
+			Membership: localMembership.GetCopy(),
+		}
+
+	case "key_op", "key_command":
+		// ✅ Operazione sulle chiavi: da un altro membro (solo locale) o da un operatore (tutto il cluster)
+		var keyMessage util.KeyMessage
+		if err := json.Unmarshal(data, &keyMessage); err != nil {
+			util.Warn(fmt.Sprintf("[KEYRING] Errore parsing messaggio %s: %v", messageType.Type, err))
+			return
+		}
+		// I comandi dell'operatore (key_command) non vengono da un nodo: basta la catena TLS
+		if messageType.Type == "key_op" && !verifier.VerifyStreamPeer("key_op", conn, keyMessage.Sender) {
+			return
+		}
+		if keyManager == nil {
+			self := localMembership.Self()
+			response = util.KeyMessage{
+				Type:    "key_response",
+				Op:      keyMessage.Op,
+				Sender:  self.ID,
+				Results: map[string]util.KeyResult{self.ID: {Error: "cifratura non attiva su questo nodo"}},
+			}
+			break
+		}
+		response = keyManager.HandleKeyMessage(keyMessage)
+
+	default:
+		util.Warn(fmt.Sprintf("[GOSSIP] Tipo messaggio sconosciuto su TCP: %s da %s", messageType.Type, conn.RemoteAddr()))
+		return
+	}
+
+	responseData, err := json.Marshal(response)
+	if err != nil {
+		util.Error(fmt.Sprintf("[GOSSIP] Errore serializzazione risposta a %s: %v", messageType.Type, err))
+		return
+	}
+	if err := transport.WriteFrame(conn, responseData); err != nil {
+		util.Warn(fmt.Sprintf("[GOSSIP] Errore invio risposta a %s a %s: %v", messageType.Type, conn.RemoteAddr(), err))
+		return
+	}
+	if reply, ok := response.(util.GossipMessage); ok && reply.Type == "join_ack" {
+		util.Debug(fmt.Sprintf("[JOIN] JOIN_ACK inviato a %s", conn.RemoteAddr()))
+	}
+}
+
+// ✅ Push-pull su TCP con un peer: invia il proprio stato e applica quello ricevuto in risposta
+func exchangeState(clk clock.Clock, nodeTransport transport.Transport, target util.NodeStatus, message util.GossipMessage, localMembership *membership.MembershipList, verifier *SenderVerifier) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		util.Error(fmt.Sprintf("[GOSSIP] Errore serializzazione messaggio: %v", err))
+		return
+	}
+
+	ctx, cancel := clock.WithTimeout(context.Background(), clk, streamTimeout)
+	defer cancel()
+
+	responseData, err := transport.Exchange(transport.WithPeerName(ctx, target.ID), nodeTransport, target.Address(), data)
+	if err != nil {
+		util.Warn(fmt.Sprintf("[GOSSIP] Errore push-pull con %s: %v", target.ID, err))
+		return
+	}
+
+	var response util.GossipMessage
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		util.Warn(fmt.Sprintf("[GOSSIP] Errore parsing risposta push-pull da %s: %v", target.ID, err))
+		return
+	}
+	if !verifier.Verify("gossip_update", response.Sender, target.Address()) {
+		return
+	}
+	mergeGossipUpdate(response, localMembership)
+}
+
+// ✅ Merge di un Gossip Update (intera membership o confutazione ALIVE) con mittente già verificato
+func mergeGossipUpdate(message util.GossipMessage, localMembership *membership.MembershipList) {
+	util.Debug(fmt.Sprintf("[GOSSIP] Ricevuto %s da %s con %d nodi.", message.Type, message.Sender.ID, len(message.Membership)))
+
+	// Aggiorna la Membership List locale (merge)
+	for _, node := range message.Membership {
+		localMembership.AddOrUpdateNode(node)
+	}
+
+	// Aggiorna anche l'ultimo visto del mittente (heartbeat implicito)
+	localMembership.UpdateLastSeen(message.Sender.ID)
+}
@@ -0,0 +1,130 @@
+// Package identity firma le entry della Membership List con una chiave Ed25519 per nodo.
+//
+// Ogni nodo possiede una chiave privata di identità e un certificato: la firma di una
+// CA fidata sul nome del nodo e sulla sua chiave pubblica. Il nodo firma la propria entry
+// (ID, indirizzo, incarnation, heartbeat e uscita volontaria); chi la riceve verifica il
+// certificato con le chiavi delle CA fidate e poi la firma con la chiave del nodo.
+// Un peer non può quindi inventare membri, cambiarne l'indirizzo o alzarne incarnation e
+// heartbeat. Le accuse SWIM (SUSPECT e DEAD) conservano la firma dell'ultima entry ALIVE:
+// lo stato di sospetto non è firmato, perché viene deciso dagli altri membri.
+package identity
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"Gossip/internal/util"
+)
+
+// Errori di verifica di una entry
+var (
+	ErrUnsigned     = errors.New("entry non firmata")
+	ErrUntrusted    = errors.New("certificato non emesso da una CA fidata")
+	ErrBadSignature = errors.New("firma dell'entry non valida")
+)
+
+// Prefissi che separano i due tipi di messaggi firmati
+const (
+	certificateContext = "gossip-certificate-v1"
+	entryContext       = "gossip-entry-v1"
+)
+
+// ✅ Identity: chiave di identità del nodo locale e CA fidate per verificare gli altri membri
+type Identity struct {
+	privateKey  ed25519.PrivateKey
+	certificate []byte
+	trusted     []ed25519.PublicKey
+}
+
+// Costruttore: crea l'identità del nodo name. Il certificato deve essere emesso
+// da una delle CA fidate, altrimenti gli altri membri rifiuterebbero le nostre entry
+func New(name string, privateKey ed25519.PrivateKey, certificate []byte, trusted []ed25519.PublicKey) (*Identity, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("chiave di identità di %d byte (attesi %d)", len(privateKey), ed25519.PrivateKeySize)
+	}
+	if len(trusted) == 0 {
+		return nil, errors.New("nessuna CA fidata")
+	}
+	for _, key := range trusted {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("chiave di CA di %d byte (attesi %d)", len(key), ed25519.PublicKeySize)
+		}
+	}
+
+	id := &Identity{
+		privateKey:  privateKey,
+		certificate: certificate,
+		trusted:     trusted,
+	}
+	if !id.trustedCertificate(name, privateKey.Public().(ed25519.PublicKey), certificate) {
+		return nil, fmt.Errorf("identità di %s: %w", name, ErrUntrusted)
+	}
+	return id, nil
+}
+
+// ✅ Certificato per il nodo name: firma della CA sul nome e sulla chiave pubblica del nodo
+func Certify(caKey ed25519.PrivateKey, name string, publicKey ed25519.PublicKey) []byte {
+	return ed25519.Sign(caKey, certificatePayload(name, publicKey))
+}
+
+// ✅ Firma l'entry del nodo locale (chiave pubblica e certificato viaggiano con l'entry)
+func (id *Identity) Sign(node util.NodeStatus) util.NodeStatus {
+	node.PublicKey = id.privateKey.Public().(ed25519.PublicKey)
+	node.Certificate = id.certificate
+	node.Signature = ed25519.Sign(id.privateKey, entryPayload(node))
+	return node
+}
+
+// ✅ Verifica certificato e firma di una entry ricevuta
+func (id *Identity) Verify(node util.NodeStatus) error {
+	if len(node.Signature) == 0 || len(node.PublicKey) != ed25519.PublicKeySize {
+		return ErrUnsigned
+	}
+	if !id.trustedCertificate(node.ID, node.PublicKey, node.Certificate) {
+		return ErrUntrusted
+	}
+	if !ed25519.Verify(node.PublicKey, entryPayload(node), node.Signature) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ✅ true se certificate è la firma di una CA fidata sul nome e sulla chiave
+func (id *Identity) trustedCertificate(name string, publicKey ed25519.PublicKey, certificate []byte) bool {
+	payload := certificatePayload(name, publicKey)
+	for _, caKey := range id.trusted {
+		if ed25519.Verify(caKey, payload, certificate) {
+			return true
+		}
+	}
+	return false
+}
+
+func certificatePayload(name string, publicKey ed25519.PublicKey) []byte {
+	payload := appendField([]byte(certificateContext), []byte(name))
+	return appendField(payload, publicKey)
+}
+
+// ✅ Campi firmati di una entry: tutto ciò che solo il nodo stesso può dichiarare.
+// SUSPECT e DEAD sono accuse degli altri membri: valgono come la entry ALIVE firmata
+func entryPayload(node util.NodeStatus) []byte {
+	claim := "alive"
+	if node.Status == "left" {
+		claim = "left"
+	}
+
+	payload := []byte(entryContext)
+	for _, field := range []string{node.ID, node.IP, node.Port, claim} {
+		payload = appendField(payload, []byte(field))
+	}
+	payload = binary.BigEndian.AppendUint64(payload, node.Incarnation)
+	return binary.BigEndian.AppendUint64(payload, node.Heartbeat)
+}
+
+// Campo preceduto dalla lunghezza: nessuna ambiguità tra campi adiacenti
+func appendField(payload, field []byte) []byte {
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(field)))
+	return append(payload, field...)
+}
@@ -0,0 +1,98 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"Gossip/internal/util"
+)
+
+// ✅ Chiave Ed25519 deterministica
+func testKey(b byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{b}, ed25519.SeedSize))
+}
+
+// ✅ Identità del nodo name con certificato emesso da ca
+func testIdentity(t *testing.T, ca ed25519.PrivateKey, name string, key ed25519.PrivateKey) *Identity {
+	t.Helper()
+	certificate := Certify(ca, name, key.Public().(ed25519.PublicKey))
+	id, err := New(name, key, certificate, []ed25519.PublicKey{ca.Public().(ed25519.PublicKey)})
+	if err != nil {
+		t.Fatalf("New %s: %v", name, err)
+	}
+	return id
+}
+
+func TestSignVerify(t *testing.T) {
+	ca := testKey(1)
+	node1 := testIdentity(t, ca, "node1", testKey(2))
+	node2 := testIdentity(t, ca, "node2", testKey(3))
+
+	entry := node1.Sign(util.NodeStatus{ID: "node1", IP: "10.0.0.1", Port: "9000", Status: "alive", Incarnation: 2, Heartbeat: 7})
+	if err := node2.Verify(entry); err != nil {
+		t.Fatalf("entry firmata: %v", err)
+	}
+
+	// Le accuse SWIM conservano la firma della entry ALIVE
+	for _, status := range []string{"suspect", "dead"} {
+		accused := entry
+		accused.Status = status
+		if err := node2.Verify(accused); err != nil {
+			t.Errorf("entry %s: %v", status, err)
+		}
+	}
+
+	// Tutto ciò che solo il nodo può dichiarare è coperto dalla firma
+	tampered := map[string]func(*util.NodeStatus){
+		"incarnation": func(n *util.NodeStatus) { n.Incarnation++ },
+		"heartbeat":   func(n *util.NodeStatus) { n.Heartbeat++ },
+		"indirizzo":   func(n *util.NodeStatus) { n.IP = "10.0.0.66" },
+		"left":        func(n *util.NodeStatus) { n.Status = "left" },
+	}
+	for field, tamper := range tampered {
+		forged := entry
+		tamper(&forged)
+		if err := node2.Verify(forged); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s alterato: errore %v", field, err)
+		}
+	}
+
+	left := entry
+	left.Status = "left"
+	if err := node2.Verify(node1.Sign(left)); err != nil {
+		t.Errorf("LEFT firmato dal nodo: %v", err)
+	}
+
+	unsigned := util.NodeStatus{ID: "node3", IP: "10.0.0.3", Port: "9000", Status: "alive"}
+	if err := node2.Verify(unsigned); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("entry non firmata: errore %v", err)
+	}
+}
+
+func TestUntrustedIdentity(t *testing.T) {
+	ca := testKey(1)
+	verifier := testIdentity(t, ca, "node1", testKey(2))
+
+	// Nodo certificato da un'altra CA
+	outsider := testIdentity(t, testKey(9), "node2", testKey(3))
+	entry := outsider.Sign(util.NodeStatus{ID: "node2", IP: "10.0.0.2", Port: "9000", Status: "alive"})
+	if err := verifier.Verify(entry); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("CA non fidata: errore %v", err)
+	}
+
+	// Un membro certificato non può firmare entry a nome di un altro
+	member := testIdentity(t, ca, "node2", testKey(3))
+	impersonated := member.Sign(util.NodeStatus{ID: "node3", IP: "10.0.0.3", Port: "9000", Status: "alive"})
+	if err := verifier.Verify(impersonated); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("entry di un altro nodo: errore %v", err)
+	}
+
+	// Un certificato per un altro nome non viene accettato neanche all'avvio
+	key := testKey(4)
+	certificate := Certify(ca, "node4", key.Public().(ed25519.PublicKey))
+	if _, err := New("node5", key, certificate, []ed25519.PublicKey{ca.Public().(ed25519.PublicKey)}); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("certificato per un altro nome: errore %v", err)
+	}
+}
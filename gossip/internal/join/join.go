@@ -1,20 +1,167 @@
 package join
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net"
+	"strings"
+	"time"
 
+	"Gossip/internal/clock"
+	"Gossip/internal/failure"
 	"Gossip/internal/membership"
+	"Gossip/internal/transport"
 	"Gossip/internal/util"
 )
 
-// Funzione per inviare una richiesta di JOIN al nodo bootstrap
-func SendJoinRequest(bootstrapIP, bootstrapPort string, self util.NodeStatus, localMembership *membership.MembershipList) error {
-	addr := fmt.Sprintf("%s:%s", bootstrapIP, bootstrapPort)
+// Errori di JOIN che non si risolvono ritentando
+var (
+	ErrNameConflict     = errors.New("nome del nodo già in uso nel cluster")        // Nome usato da un altro membro attivo
+	ErrIdentityRejected = errors.New("identità del nodo non accettata dal cluster") // Entry non firmata o certificato non fidato
+)
+
+// ✅ Indica se il JOIN è stato rifiutato in modo definitivo
+func isRejection(err error) bool {
+	return errors.Is(err, ErrNameConflict) || errors.Is(err, ErrIdentityRejected)
+}
+
+// ✅ Parametri del JOIN iniziale verso i seed
+type Config struct {
+	Timeout        time.Duration // Tempo massimo complessivo per ottenere almeno una JOIN_ACK
+	AttemptTimeout time.Duration // Attesa della JOIN_ACK per singolo tentativo
+	InitialBackoff time.Duration // Attesa prima del secondo tentativo verso lo stesso seed
+	MaxBackoff     time.Duration // Limite superiore del backoff esponenziale
+	Clock          clock.Clock   // Orologio del backoff (clock.Real o virtuale nel simulatore)
+}
+
+// ✅ Configurazione di default del JOIN
+func DefaultConfig() Config {
+	return Config{
+		Timeout:        30 * time.Second,
+		AttemptTimeout: 2 * time.Second,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     8 * time.Second,
+		Clock:          clock.Real,
+	}
+}
+
+// ✅ Joiner gestisce il JOIN del nodo locale verso i seed e le richieste JOIN degli altri nodi.
+// JOIN e JOIN_ACK viaggiano su TCP, perché la JOIN_ACK contiene l'intera membership
+type Joiner struct {
+	config          Config
+	transport       transport.Transport
+	localMembership *membership.MembershipList
+	prober          *failure.Prober
+}
+
+// Costruttore: crea il Joiner del nodo locale
+func NewJoiner(config Config, nodeTransport transport.Transport, localMembership *membership.MembershipList, prober *failure.Prober) *Joiner {
+	return &Joiner{
+		config:          config,
+		transport:       nodeTransport,
+		localMembership: localMembership,
+		prober:          prober,
+	}
+}
+
+// ✅ Esegue il JOIN contattando in parallelo tutti i seed "ip:port", ognuno con backoff esponenziale.
+// Alla prima JOIN_ACK ricevuta gli altri tentativi vengono interrotti; restituisce il numero
+// di seed che hanno risposto. Se entro config.Timeout nessun seed risponde restituisce un
+// errore con l'ultimo motivo di fallimento di ciascun seed
+func (j *Joiner) JoinCluster(ctx context.Context, seeds []string) (int, error) {
+	self := j.localMembership.Self()
+
+	targets := []string{}
+	for _, seed := range seeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			return 0, fmt.Errorf("seed non valido (atteso ip:port): %s", seed)
+		}
+		// Non ha senso contattare sé stessi
+		if host == self.IP && port == self.Port {
+			continue
+		}
+		targets = append(targets, seed)
+	}
+	if len(targets) == 0 {
+		return 0, errors.New("nessun seed da contattare")
+	}
+
+	ctx, cancel := clock.WithTimeout(ctx, j.config.Clock, j.config.Timeout)
+	defer cancel()
+
+	results := make(chan error, len(targets))
+	for _, seed := range targets {
+		go func(seed string) {
+			results <- j.joinSeed(ctx, seed)
+		}(seed)
+	}
+
+	joined := 0
+	failures := []string{}
+	var rejection error
+	for range targets {
+		err := <-results
+		if err == nil {
+			// Basta un seed: gli altri tentativi vengono interrotti
+			joined++
+			cancel()
+			continue
+		}
+		if isRejection(err) && rejection == nil {
+			// Un solo rifiuto basta: nome già usato o identità non accettata
+			rejection = err
+			cancel()
+		}
+		failures = append(failures, err.Error())
+	}
 
+	if rejection != nil && joined == 0 {
+		return 0, rejection
+	}
+	if joined == 0 {
+		return 0, fmt.Errorf("nessun seed ha risposto entro %v: %s", j.config.Timeout, strings.Join(failures, "; "))
+	}
+	return joined, nil
+}
+
+// ✅ Tentativi ripetuti verso un singolo seed finché risponde o ctx scade
+func (j *Joiner) joinSeed(ctx context.Context, seed string) error {
+	backoff := j.config.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := clock.WithTimeout(ctx, j.config.Clock, j.config.AttemptTimeout)
+		err := j.SendJoinRequest(attemptCtx, seed)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		// Un conflitto di nomi o un'identità rifiutata non si risolvono ritentando
+		if isRejection(err) {
+			return fmt.Errorf("%s: %w", seed, err)
+		}
+		util.Debug(fmt.Sprintf("[JOIN] Tentativo %d verso %s fallito: %v", attempt, seed, err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %v", seed, err)
+		case <-j.config.Clock.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > j.config.MaxBackoff {
+			backoff = j.config.MaxBackoff
+		}
+	}
+}
+
+// Funzione per inviare una richiesta di JOIN al nodo bootstrap "ip:port" e attendere la JOIN_ACK
+// sulla stessa connessione TCP (al massimo fino alla scadenza di ctx)
+func (j *Joiner) SendJoinRequest(ctx context.Context, bootstrapAddr string) error {
 	// Costruisci il messaggio di JOIN
+	self := j.localMembership.Self()
 	joinMessage := util.JoinMessage{
 		Type:   "join",
 		Sender: self,
@@ -26,88 +173,104 @@ func SendJoinRequest(bootstrapIP, bootstrapPort string, self util.NodeStatus, lo
 		return fmt.Errorf("errore serializzazione messaggio JOIN: %v", err)
 	}
 
-	// Invia il messaggio UDP al nodo bootstrap
-	conn, err := net.Dial("udp", addr)
-	if err != nil {
-		return fmt.Errorf("errore connessione UDP al nodo bootstrap: %v", err)
-	}
-	defer conn.Close()
-
-	_, err = conn.Write(data)
-	if err != nil {
-		return fmt.Errorf("errore invio messaggio JOIN: %v", err)
-	}
-
-	log.Printf("[JOIN] Richiesta JOIN inviata a %s\n", addr)
+	util.Debug(fmt.Sprintf("[JOIN] Invio richiesta JOIN a %s", bootstrapAddr))
 
-	// Attesa della JOIN_ACK
-	buffer := make([]byte, 4096)
-	n, err := conn.Read(buffer)
+	// La JOIN_ACK contiene l'intera membership: viaggia su TCP
+	response, err := transport.Exchange(ctx, j.transport, bootstrapAddr, data)
 	if err != nil {
-		return fmt.Errorf("errore ricezione JOIN_ACK: %v", err)
+		return fmt.Errorf("nessuna JOIN_ACK ricevuta: %v", err)
 	}
 
-	// Deserializza la risposta
 	var ack util.GossipMessage
-	err = json.Unmarshal(buffer[:n], &ack)
-	if err != nil {
+	if err := json.Unmarshal(response, &ack); err != nil {
 		return fmt.Errorf("errore parsing JOIN_ACK: %v", err)
 	}
+	if ack.Type == "join_conflict" {
+		return fmt.Errorf("%w: %s rifiutato da %s", ErrNameConflict, self.ID, ack.Sender.ID)
+	}
+	if ack.Type == "join_rejected" {
+		return fmt.Errorf("%w: %s rifiutato da %s", ErrIdentityRejected, self.ID, ack.Sender.ID)
+	}
+	if ack.Type != "join_ack" {
+		return fmt.Errorf("risposta inattesa al JOIN: %s", ack.Type)
+	}
+
+	// ✅ Scoperta automatica dell'indirizzo annunciato (solo se non configurato):
+	// si usa l'IP da cui il seed ha visto arrivare la richiesta
+	if current := j.localMembership.Self(); current.IP == "" && ack.Observed != "" {
+		if host, _, err := net.SplitHostPort(ack.Observed); err == nil {
+			j.localMembership.UpdateSelfAddress(host, current.Port)
+			util.Info(fmt.Sprintf("[JOIN] Indirizzo annunciato scoperto tramite %s: %s", ack.Sender.ID, net.JoinHostPort(host, current.Port)))
+		}
+	}
 
 	// Aggiorna la Membership List locale con i dati ricevuti
+	bootstrapIP, _, _ := net.SplitHostPort(bootstrapAddr)
 	for _, node := range ack.Membership {
-		localMembership.AddOrUpdateNode(node)
+		// Un seed senza indirizzo annunciato è comunque raggiungibile dove lo abbiamo contattato
+		if node.ID == ack.Sender.ID && node.IP == "" {
+			node.IP = bootstrapIP
+		}
+		j.localMembership.AddOrUpdateNode(node)
+	}
+	// Heartbeat implicito solo per un mittente autentico (firma valida con le identità attive)
+	if err := j.localMembership.VerifyEntry(ack.Sender); err == nil {
+		j.localMembership.UpdateLastSeen(ack.Sender.ID)
 	}
-	log.Printf("[JOIN] Ricevuta Membership List da %s con %d nodi\n", addr, len(ack.Membership))
+	util.Debug(fmt.Sprintf("[JOIN] Ricevuta Membership List da %s con %d nodi", bootstrapAddr, len(ack.Membership)))
 
 	return nil
 }
 
 // Funzione per gestire la ricezione di una richiesta JOIN da un nuovo nodo
-func HandleJoinRequest(data []byte, addr net.Addr, localMembership *membership.MembershipList, selfNode util.NodeStatus) {
-	// Parsing del messaggio ricevuto
-	var joinMsg util.JoinMessage
-	err := json.Unmarshal(data, &joinMsg)
-	if err != nil {
-		log.Printf("[JOIN] Errore parsing JOIN ricevuto: %v", err)
-		return
-	}
-
+// Se il nome è già usato da un membro attivo con un altro indirizzo, il vecchio indirizzo
+// viene sondato (la risposta attende al più ProbeTimeout): è un conflitto di nomi (JOIN
+// rifiutato) solo se risponde il membro stesso, con quel nome e ancora quell'indirizzo.
+// Altrimenti (nessuna risposta, o all'indirizzo ora c'è un altro nodo) il nodo ha solo
+// cambiato indirizzo e la sua entry verrà aggiornata
+// La risposta (JOIN_ACK o rifiuto) viene restituita al server TCP che la invia sulla stessa connessione
+func (j *Joiner) HandleJoinRequest(joinMsg util.JoinMessage, addr net.Addr) util.GossipMessage {
 	newNode := joinMsg.Sender
-	log.Printf("[JOIN] Ricevuta richiesta JOIN da %s (%s:%s)\n", newNode.ID, newNode.IP, newNode.Port)
 
-	// Aggiungi il nuovo nodo alla Membership List locale
-	localMembership.AddOrUpdateNode(newNode)
-
-	// Prepara JOIN_ACK con Membership List attuale
-	membershipList := localMembership.GetCopy()
-
-	joinAck := util.GossipMessage{
-		Type:       "join_ack",
-		Sender:     selfNode,
-		Membership: membershipList,
+	// ✅ Nodo che non conosce ancora il proprio indirizzo: si usa quello osservato
+	if newNode.IP == "" {
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			newNode.IP = host
+		}
 	}
+	util.Debug(fmt.Sprintf("[JOIN] Ricevuta richiesta JOIN da %s (%s)", newNode.ID, newNode.Address()))
 
-	// Serializza JOIN_ACK
-	ackData, err := json.Marshal(joinAck)
-	if err != nil {
-		log.Printf("[JOIN] Errore serializzazione JOIN_ACK: %v", err)
-		return
+	// ✅ Con le identità attive l'entry deve essere firmata da un membro certificato
+	self := j.localMembership.Self()
+	if err := j.localMembership.VerifyEntry(newNode); err != nil {
+		util.Warn(fmt.Sprintf("[JOIN] JOIN da %s (%s) rifiutato: %v", newNode.ID, newNode.Address(), err))
+		return util.GossipMessage{
+			Type:   "join_rejected",
+			Sender: self,
+		}
 	}
 
-	// Rispondi al nodo richiedente
-	conn, err := net.Dial("udp", addr.String())
-	if err != nil {
-		log.Printf("[JOIN] Errore connessione per JOIN_ACK: %v", err)
-		return
+	// ✅ Controllo conflitto di nomi
+	if existing, exists := j.localMembership.GetNode(newNode.ID); exists && !membership.IsTombstone(existing.Status) && existing.Address() != newNode.Address() {
+		if existing.ID == self.ID || j.prober.Ping(existing) {
+			util.Warn(fmt.Sprintf("[JOIN] Conflitto di nomi: %s è già usato da %s, JOIN da %s rifiutato", newNode.ID, existing.Address(), newNode.Address()))
+			return util.GossipMessage{
+				Type:   "join_conflict",
+				Sender: self,
+			}
+		}
+		util.Info(fmt.Sprintf("[JOIN] Nodo %s ha cambiato indirizzo: %s → %s", newNode.ID, existing.Address(), newNode.Address()))
 	}
-	defer conn.Close()
 
-	_, err = conn.Write(ackData)
-	if err != nil {
-		log.Printf("[JOIN] Errore invio JOIN_ACK: %v", err)
-		return
-	}
+	// Aggiungi il nuovo nodo alla Membership List locale
+	j.localMembership.AddOrUpdateNode(newNode)
 
-	log.Printf("[JOIN] JOIN_ACK inviato a %s\n", addr.String())
+	// Prepara JOIN_ACK con Membership List attuale: se contiene una vecchia entry del nodo
+	// (altro indirizzo o incarnation precedente) il nodo la supererà con una nuova incarnation
+	return util.GossipMessage{
+		Type:       "join_ack",
+		Sender:     self,
+		Membership: j.localMembership.GetCopy(),
+		Observed:   addr.String(),
+	}
 }
@@ -0,0 +1,201 @@
+// Package keyring gestisce le chiavi simmetriche condivise con cui viene cifrato
+// tutto il traffico tra i nodi (AES-GCM): una chiave primaria per cifrare e altre
+// chiavi accettate in decifratura, così le chiavi possono essere ruotate senza fermare il cluster.
+package keyring
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	version   = 1  // Primo byte dei messaggi cifrati
+	nonceSize = 12 // Nonce standard di AES-GCM
+	tagSize   = 16 // Tag di autenticazione di AES-GCM
+
+	// Byte aggiunti dalla cifratura a ogni messaggio
+	Overhead = 1 + nonceSize + tagSize
+)
+
+// Errore di decifratura: nessuna chiave del keyring decifra il messaggio
+var ErrUndecryptable = errors.New("messaggio non decifrabile con le chiavi installate")
+
+// ✅ Keyring: chiavi AES da 16, 24 o 32 byte; la prima è la primaria
+type Keyring struct {
+	mutex sync.RWMutex
+	keys  [][]byte
+}
+
+// Costruttore: crea un keyring con le chiavi indicate (la prima diventa la primaria)
+func New(keys [][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring vuoto: serve almeno una chiave")
+	}
+
+	k := &Keyring{}
+	for _, key := range keys {
+		if err := k.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// ✅ Decodifica chiavi in base64 (formato di file di configurazione e variabili d'ambiente)
+func DecodeKeys(encoded []string) ([][]byte, error) {
+	keys := [][]byte{}
+	for _, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("chiave non in base64: %v", err)
+		}
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ✅ Controlla che la chiave abbia una lunghezza AES valida
+func ValidateKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	}
+	return fmt.Errorf("chiave di %d byte non valida (ammessi 16, 24 o 32)", len(key))
+}
+
+// ✅ Installa una chiave accettata in decifratura (diventa primaria se il keyring è vuoto)
+func (k *Keyring) AddKey(key []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.indexLocked(key) >= 0 {
+		return nil
+	}
+	k.keys = append(k.keys, append([]byte(nil), key...))
+	return nil
+}
+
+// ✅ Rende primaria una chiave già installata
+func (k *Keyring) UseKey(key []byte) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	index := k.indexLocked(key)
+	if index < 0 {
+		return errors.New("chiave non installata")
+	}
+	primary := k.keys[index]
+	copy(k.keys[1:index+1], k.keys[:index])
+	k.keys[0] = primary
+	return nil
+}
+
+// ✅ Rimuove una chiave (la primaria non può essere rimossa)
+func (k *Keyring) RemoveKey(key []byte) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	index := k.indexLocked(key)
+	switch {
+	case index < 0:
+		return errors.New("chiave non installata")
+	case index == 0:
+		return errors.New("la chiave primaria non può essere rimossa")
+	}
+	k.keys = append(k.keys[:index], k.keys[index+1:]...)
+	return nil
+}
+
+// ✅ Chiavi installate (copia), la primaria per prima
+func (k *Keyring) Keys() [][]byte {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	keys := make([][]byte, len(k.keys))
+	for i, key := range k.keys {
+		keys[i] = append([]byte(nil), key...)
+	}
+	return keys
+}
+
+// ✅ Chiave primaria (copia)
+func (k *Keyring) PrimaryKey() []byte {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return append([]byte(nil), k.keys[0]...)
+}
+
+// ✅ Cifra con la chiave primaria: versione, nonce casuale, testo cifrato con tag
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return k.EncryptWithData(plaintext, nil)
+}
+
+// ✅ Come Encrypt, ma il tag autentica anche additionalData (non cifrati né trasmessi):
+// il messaggio si decifra solo con gli stessi dati, es. il contesto in cui è stato inviato
+func (k *Keyring) EncryptWithData(plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(k.PrimaryKey())
+	if err != nil {
+		return nil, err
+	}
+
+	message := make([]byte, 1+nonceSize, Overhead+len(plaintext))
+	message[0] = version
+	if _, err := rand.Read(message[1:]); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(message, message[1:], plaintext, additionalData), nil
+}
+
+// ✅ Decifra provando tutte le chiavi installate, a partire dalla primaria
+func (k *Keyring) Decrypt(message []byte) ([]byte, error) {
+	return k.DecryptWithData(message, nil)
+}
+
+// ✅ Decifra un messaggio di EncryptWithData (ErrUndecryptable se additionalData non coincidono)
+func (k *Keyring) DecryptWithData(message, additionalData []byte) ([]byte, error) {
+	if len(message) < Overhead || message[0] != version {
+		return nil, ErrUndecryptable
+	}
+	nonce, ciphertext := message[1:1+nonceSize], message[1+nonceSize:]
+
+	for _, key := range k.Keys() {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		if plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrUndecryptable
+}
+
+func (k *Keyring) indexLocked(key []byte) int {
+	for i, installed := range k.keys {
+		if bytes.Equal(installed, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
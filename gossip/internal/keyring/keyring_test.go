@@ -0,0 +1,93 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := New([][]byte{testKey(1)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	message, err := k.Encrypt([]byte(`{"type":"ping"}`))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if bytes.Contains(message, []byte("ping")) {
+		t.Fatal("messaggio cifrato contiene il testo in chiaro")
+	}
+	plaintext, err := k.Decrypt(message)
+	if err != nil || string(plaintext) != `{"type":"ping"}` {
+		t.Fatalf("Decrypt: %q, %v", plaintext, err)
+	}
+
+	// Messaggi alterati, in chiaro o con un'altra chiave vengono rifiutati
+	message[len(message)-1] ^= 1
+	if _, err := k.Decrypt(message); !errors.Is(err, ErrUndecryptable) {
+		t.Errorf("messaggio alterato: errore %v", err)
+	}
+	if _, err := k.Decrypt([]byte(`{"type":"ping"}`)); !errors.Is(err, ErrUndecryptable) {
+		t.Errorf("messaggio in chiaro: errore %v", err)
+	}
+	other, _ := New([][]byte{testKey(2)})
+	foreign, _ := other.Encrypt([]byte("x"))
+	if _, err := k.Decrypt(foreign); !errors.Is(err, ErrUndecryptable) {
+		t.Errorf("chiave sconosciuta: errore %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, newKey := testKey(1), testKey(2)
+	k, _ := New([][]byte{oldKey})
+	peer, _ := New([][]byte{oldKey})
+
+	// Installazione: la nuova chiave viene accettata ma non ancora usata
+	if err := k.AddKey(newKey); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if !bytes.Equal(k.PrimaryKey(), oldKey) {
+		t.Fatal("AddKey ha cambiato la chiave primaria")
+	}
+
+	// Uso: i messaggi nuovi sono cifrati con la nuova chiave, i vecchi restano leggibili
+	old, _ := peer.Encrypt([]byte("vecchio"))
+	if err := k.UseKey(newKey); err != nil {
+		t.Fatalf("UseKey: %v", err)
+	}
+	if plaintext, err := k.Decrypt(old); err != nil || string(plaintext) != "vecchio" {
+		t.Fatalf("messaggio con la vecchia chiave: %q, %v", plaintext, err)
+	}
+	if err := k.RemoveKey(newKey); err == nil {
+		t.Fatal("rimossa la chiave primaria")
+	}
+
+	// Rimozione: la vecchia chiave non viene più accettata
+	if err := k.RemoveKey(oldKey); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	if _, err := k.Decrypt(old); !errors.Is(err, ErrUndecryptable) {
+		t.Fatalf("vecchia chiave ancora accettata: %v", err)
+	}
+	if keys := k.Keys(); len(keys) != 1 || !bytes.Equal(keys[0], newKey) {
+		t.Fatalf("chiavi installate: %d", len(keys))
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("keyring vuoto accettato")
+	}
+	if _, err := New([][]byte{[]byte("corta")}); err == nil {
+		t.Error("chiave di lunghezza non valida accettata")
+	}
+	if _, err := DecodeKeys([]string{"non base64!"}); err == nil {
+		t.Error("chiave non in base64 accettata")
+	}
+}
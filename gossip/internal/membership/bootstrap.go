@@ -0,0 +1,79 @@
+package membership
+
+import (
+	"net"
+	"sort"
+
+	"Gossip/internal/util"
+)
+
+// ✅ Registra gli indirizzi "ip:port" dei seed da contattare per il bootstrap.
+// I seed NON entrano nella Membership List (e quindi non vengono diffusi via gossip)
+// finché non rispondono davvero a un JOIN o a un ping
+func (ml *MembershipList) AddBootstrapAddresses(addresses []string) int {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+
+	added := 0
+	for _, address := range addresses {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			continue
+		}
+		// Seed già verificati o coincidenti con il nodo locale non servono
+		if ml.hasAddressLocked(address) {
+			continue
+		}
+		if !ml.bootstrap[address] {
+			ml.bootstrap[address] = true
+			added++
+		}
+	}
+	return added
+}
+
+// ✅ Seed non ancora verificati (ordinati), da ricontattare nei tentativi di JOIN successivi
+func (ml *MembershipList) BootstrapAddresses() []string {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	addresses := make([]string, 0, len(ml.bootstrap))
+	for address := range ml.bootstrap {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// ✅ Promuove nella Membership List un nodo che ha risposto da un indirizzo di bootstrap
+// (es. ping o ack ricevuto da un seed). L'indirizzo annunciato deve coincidere sia con un seed
+// sia con la sorgente effettiva del pacchetto (source): un messaggio non può far entrare un
+// nodo a nome di un seed da cui non proviene. I seed indicati per hostname o dietro NAT non
+// vengono promossi qui ma entrano con la JOIN_ACK o con il gossip
+func (ml *MembershipList) PromoteBootstrapNode(node util.NodeStatus, source string) bool {
+	ml.mutex.Lock()
+	defer ml.unlockAndNotify()
+
+	address := net.JoinHostPort(node.IP, node.Port)
+	if !ml.bootstrap[address] || address != source {
+		return false
+	}
+	ml.addOrUpdateLocked(node)
+	return true
+}
+
+// ✅ Rimuove dalla lista di bootstrap l'indirizzo di un nodo ormai noto (il chiamante deve possedere il lock)
+func (ml *MembershipList) promoteBootstrapLocked(node util.NodeStatus) {
+	if len(ml.bootstrap) > 0 {
+		delete(ml.bootstrap, net.JoinHostPort(node.IP, node.Port))
+	}
+}
+
+// ✅ Indica se un membro (non tombstone) usa già l'indirizzo dato (il chiamante deve possedere il lock)
+func (ml *MembershipList) hasAddressLocked(address string) bool {
+	for _, node := range ml.members {
+		if !IsTombstone(node.Status) && net.JoinHostPort(node.IP, node.Port) == address {
+			return true
+		}
+	}
+	return false
+}
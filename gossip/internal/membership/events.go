@@ -0,0 +1,160 @@
+package membership
+
+import (
+	"sync"
+
+	"Gossip/internal/util"
+)
+
+// ✅ EventDelegate riceve le transizioni di stato dei membri.
+// Ogni transizione viene notificata una sola volta, FUORI dal lock della
+// Membership List e nello stesso ordine in cui è stata applicata: le chiamate
+// non sono mai concorrenti. Il delegate può interrogare (e modificare) la lista,
+// ma deve essere veloce perché blocca la consegna degli eventi successivi.
+type EventDelegate interface {
+	NotifyJoin(node util.NodeStatus)    // Nodo nuovo (o tornato ALIVE da un tombstone)
+	NotifyUpdate(node util.NodeStatus)  // Nodo ALIVE con incarnation/indirizzo cambiati o sospetto confutato
+	NotifySuspect(node util.NodeStatus) // Nodo diventato SUSPECT
+	NotifyLeave(node util.NodeStatus)   // Nodo uscito volontariamente (LEFT) o rimosso dalla lista
+	NotifyDead(node util.NodeStatus)    // Nodo dichiarato DEAD
+}
+
+type eventKind int
+
+const (
+	eventJoin eventKind = iota
+	eventUpdate
+	eventSuspect
+	eventLeave
+	eventDead
+)
+
+// ✅ Evento in attesa di essere consegnato al delegate
+type event struct {
+	kind     eventKind
+	node     util.NodeStatus
+	delegate EventDelegate // delegate attivo quando è avvenuta la transizione
+}
+
+// ✅ Coda ordinata degli eventi da consegnare: una sola goroutine alla volta la svuota
+type eventQueue struct {
+	mutex      sync.Mutex
+	events     []event
+	delivering bool // una goroutine sta già consegnando gli eventi in coda
+}
+
+// ✅ Imposta il delegate per gli eventi di membership (nil per disattivarlo)
+func (ml *MembershipList) SetEventDelegate(delegate EventDelegate) {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+
+	ml.delegate = delegate
+}
+
+// ✅ Registra l'evento corrispondente alla transizione previous → current (il chiamante deve possedere il lock)
+// existed = false indica un nodo appena aggiunto alla lista
+func (ml *MembershipList) recordTransitionLocked(previous util.NodeStatus, existed bool, current util.NodeStatus) {
+	if ml.delegate == nil {
+		return
+	}
+
+	kind, changed := transitionKind(previous, existed, current)
+	if !changed {
+		return
+	}
+	current.LastSeen = ""
+	current.StatusSince = ""
+	ml.events = append(ml.events, event{kind: kind, node: current})
+}
+
+// ✅ Registra la rimozione di un nodo (il chiamante deve possedere il lock)
+// I tombstone sono già stati notificati come LEAVE/DEAD: la loro rimozione non genera eventi
+func (ml *MembershipList) recordRemovalLocked(node util.NodeStatus) {
+	if ml.delegate == nil || IsTombstone(node.Status) {
+		return
+	}
+	node.LastSeen = ""
+	node.StatusSince = ""
+	ml.events = append(ml.events, event{kind: eventLeave, node: node})
+}
+
+// ✅ Classifica una transizione di stato; changed = false se non c'è nulla da notificare
+// (es. solo un nuovo heartbeat)
+func transitionKind(previous util.NodeStatus, existed bool, current util.NodeStatus) (eventKind, bool) {
+	if !existed || (IsTombstone(previous.Status) && !IsTombstone(current.Status)) {
+		return eventJoin, true
+	}
+
+	if current.Status != previous.Status {
+		switch current.Status {
+		case "suspect":
+			return eventSuspect, true
+		case "dead":
+			return eventDead, true
+		case "left":
+			return eventLeave, true
+		default:
+			return eventUpdate, true
+		}
+	}
+
+	if current.Incarnation != previous.Incarnation || current.IP != previous.IP || current.Port != previous.Port {
+		return eventUpdate, true
+	}
+	return 0, false
+}
+
+// ✅ Rilascia il lock e consegna al delegate gli eventi accumulati durante l'operazione.
+// Va usato al posto di mutex.Unlock nei metodi che modificano la lista.
+// Gli eventi entrano nella coda prima di rilasciare il lock, quindi nell'ordine delle
+// transizioni; se un'altra goroutine sta già consegnando, li consegnerà lei dopo i propri
+func (ml *MembershipList) unlockAndNotify() {
+	events := ml.events
+	ml.events = nil
+	if len(events) == 0 {
+		ml.mutex.Unlock()
+		return
+	}
+	for i := range events {
+		events[i].delegate = ml.delegate
+	}
+
+	ml.queue.mutex.Lock()
+	ml.queue.events = append(ml.queue.events, events...)
+	deliver := !ml.queue.delivering
+	ml.queue.delivering = true
+	ml.queue.mutex.Unlock()
+	ml.mutex.Unlock()
+
+	if deliver {
+		ml.queue.deliver()
+	}
+}
+
+// ✅ Consegna gli eventi in coda finché non è vuota (anche quelli aggiunti nel frattempo)
+func (q *eventQueue) deliver() {
+	for {
+		q.mutex.Lock()
+		if len(q.events) == 0 {
+			q.delivering = false
+			q.mutex.Unlock()
+			return
+		}
+		e := q.events[0]
+		q.events = q.events[1:]
+		q.mutex.Unlock()
+
+		switch e.kind {
+		case eventJoin:
+			e.delegate.NotifyJoin(e.node)
+		case eventUpdate:
+			e.delegate.NotifyUpdate(e.node)
+		case eventSuspect:
+			e.delegate.NotifySuspect(e.node)
+		case eventLeave:
+			e.delegate.NotifyLeave(e.node)
+		case eventDead:
+			e.delegate.NotifyDead(e.node)
+		}
+	}
+}
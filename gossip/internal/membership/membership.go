@@ -2,125 +2,385 @@ package membership
 
 import (
 	"Gossip/internal/util"
+	"fmt"
 	"sync"
 	"time"
+
+	"Gossip/internal/clock"
 )
 
 // Struttura della Membership List
 type MembershipList struct {
-	members map[string]util.NodeStatus // mappa da ID nodo a NodeStatus
-	mutex   sync.RWMutex               // mutex per accesso concorrente sicuro
+	members  map[string]util.NodeStatus // mappa da ID nodo a NodeStatus
+	mutex    sync.RWMutex               // mutex per accesso concorrente sicuro
+	selfID   string                     // ID del nodo locale (solo lui può aggiornare la propria entry)
+	refuteCh chan struct{}              // segnala che il nodo locale ha confutato un sospetto
+	rumours  *rumourQueue               // cambiamenti di stato da diffondere via piggyback
+	delegate EventDelegate              // destinatario degli eventi di membership (opzionale)
+	events   []event                    // eventi in attesa di consegna al rilascio del lock
+	queue    eventQueue                 // eventi da consegnare al delegate, in ordine
+
+	bootstrap map[string]bool // indirizzi "ip:port" dei seed non ancora verificati
+	clock     clock.Clock     // orologio dei timestamp LastSeen locali
+
+	signer        Signer // firma della propria entry e verifica di quelle ricevute (opzionale)
+	trustedClaims bool   // le dichiarazioni di un nodo su sé stesso sono autenticate dal trasporto
+	rejected      uint64 // entry ricevute scartate perché non autentiche
+}
+
+// ✅ Signer firma la entry del nodo locale e verifica quelle degli altri membri (vedi internal/identity)
+type Signer interface {
+	Sign(node util.NodeStatus) util.NodeStatus
+	Verify(node util.NodeStatus) error
 }
 
-// Costruttore: crea una nuova Membership List vuota
-func NewMembershipList() *MembershipList {
+// Costruttore: crea una nuova Membership List vuota per il nodo locale selfID
+// (clk è l'orologio dei timestamp LastSeen: clock.Real, o virtuale nei test e nel simulatore)
+func NewMembershipList(selfID string, clk clock.Clock) *MembershipList {
 	return &MembershipList{
-		members: make(map[string]util.NodeStatus),
+		members:  make(map[string]util.NodeStatus),
+		selfID:   selfID,
+		refuteCh: make(chan struct{}, 1),
+		rumours:  newRumourQueue(),
+
+		bootstrap: make(map[string]bool),
+		clock:     clk,
 	}
 }
 
+// ✅ Attiva firma e verifica delle entry (da chiamare prima di aggiungere il nodo locale)
+func (ml *MembershipList) SetSigner(signer Signer) {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+
+	ml.signer = signer
+}
+
+// ✅ Indica se i messaggi ricevuti sono autenticati anche senza identità: vero con il keyring
+// (solo i membri conoscono la chiave) o se l'operatore ha scelto esplicitamente di fidarsi
+// della rete. Altrimenti nessuno può provare di essere il nodo che dichiara la propria uscita
+func (ml *MembershipList) SetTrustedClaims(trusted bool) {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+
+	ml.trustedClaims = trusted
+}
+
+// ✅ true se le dichiarazioni di un nodo su sé stesso (LEAVE, confutazione ALIVE, entry LEFT)
+// sono autentiche: firmate con le identità attive, oppure autenticate dal trasporto
+func (ml *MembershipList) SelfClaimsAuthenticated() bool {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	return ml.signer != nil || ml.trustedClaims
+}
+
+// ✅ Numero di entry ricevute e scartate perché non autentiche (firma non valida, o LEFT
+// non autenticabile)
+func (ml *MembershipList) RejectedEntries() uint64 {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	return ml.rejected
+}
+
+// ✅ Verifica la firma di una entry ricevuta senza applicarla (nil se le identità non sono attive)
+func (ml *MembershipList) VerifyEntry(node util.NodeStatus) error {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	if ml.signer == nil {
+		return nil
+	}
+	return ml.signer.Verify(node)
+}
+
+// ✅ Verifica una entry da applicare, contando in RejectedEntries quelle rifiutate
+// (il chiamante deve possedere il lock)
+func (ml *MembershipList) verifyLocked(node util.NodeStatus) error {
+	if ml.signer == nil {
+		return nil
+	}
+	if err := ml.signer.Verify(node); err != nil {
+		ml.rejected++
+		return err
+	}
+	return nil
+}
+
+// ✅ Firma la entry del nodo locale dopo ogni modifica (il chiamante deve possedere il lock)
+func (ml *MembershipList) signLocked(self util.NodeStatus) util.NodeStatus {
+	if ml.signer == nil {
+		return self
+	}
+	return ml.signer.Sign(self)
+}
+
 // ✅ Aggiunge un nuovo nodo o aggiorna un nodo esistente
 func (ml *MembershipList) AddOrUpdateNode(node util.NodeStatus) {
 	ml.mutex.Lock()
-	defer ml.mutex.Unlock()
+	defer ml.unlockAndNotify()
 
+	ml.addOrUpdateLocked(node)
+}
+
+// ✅ Applica le regole di merge (il chiamante deve possedere il lock)
+// Il confronto usa SOLO incarnation e heartbeat: i clock dei nodi non vengono mai confrontati
+func (ml *MembershipList) addOrUpdateLocked(node util.NodeStatus) {
 	existing, exists := ml.members[node.ID]
 
-	if !exists {
-		// Nodo nuovo: aggiungilo
-		ml.members[node.ID] = node
+	// ✅ Con le identità attive ogni entry ricevuta deve essere firmata dal nodo che descrive.
+	// L'unica entry accettata senza verifica è quella iniziale del nodo locale, firmata qui
+	if node.ID == ml.selfID && !exists {
+		node = ml.signLocked(node)
+	} else if err := ml.verifyLocked(node); err != nil {
+		util.Warn(fmt.Sprintf("[MEMBERSHIP] Entry di %s (%s) scartata: %v", node.ID, node.Address(), err))
 		return
 	}
 
-	// ✅ LOGICA SMART per gestire conflitti di stato
+	// ✅ Solo il nodo stesso può dichiarare la propria uscita: senza identità né keyring una
+	// entry LEFT potrebbe venire da chiunque, quindi viene scartata e l'uscita del nodo
+	// viene rilevata dal failure detector
+	if node.Status == "left" && node.ID != ml.selfID && ml.signer == nil && !ml.trustedClaims {
+		ml.rejected++
+		util.Debug(fmt.Sprintf("[MEMBERSHIP] Entry LEFT di %s scartata: non autenticata", node.ID))
+		return
+	}
 
-	// Parse dei timestamp per confronto
-	existingTime, errExisting := time.Parse(time.RFC3339, existing.LastSeen)
-	newTime, errNew := time.Parse(time.RFC3339, node.LastSeen)
+	// ✅ Le informazioni sul nodo locale arrivate da altri non vengono mai applicate:
+	// se sono un'accusa (suspect/dead) va confutata
+	if exists && node.ID == ml.selfID {
+		ml.refuteLocked(existing, node)
+		return
+	}
 
-	if errExisting != nil || errNew != nil {
-		// Se errore nel parsing, usa quello più recente come stringa
-		if node.LastSeen > existing.LastSeen {
-			ml.members[node.ID] = node
+	if !exists {
+		// ✅ Tombstone di un nodo mai conosciuto (o già eliminato): non va reintrodotto
+		if IsTombstone(node.Status) {
+			return
 		}
+
+		// Nodo nuovo: aggiungilo con il timestamp locale di ricezione
+		node.LastSeen = ml.clock.Now().Format(time.RFC3339Nano)
+		ml.storeLocked(existing, false, node)
+		ml.rumours.enqueue(node) // JOIN
+		ml.recordTransitionLocked(existing, false, node)
+		ml.promoteBootstrapLocked(node)
+		return
+	}
+
+	if !isNewer(existing, node) {
 		return
 	}
 
-	// ✅ REGOLE per risolvere conflitti di stato:
+	// ✅ Il LastSeen locale avanza solo se il nodo ha prodotto un nuovo heartbeat/incarnazione
+	if node.Incarnation > existing.Incarnation || node.Heartbeat > existing.Heartbeat {
+		node.LastSeen = ml.clock.Now().Format(time.RFC3339Nano)
+	} else {
+		node.LastSeen = existing.LastSeen
+	}
+	ml.storeLocked(existing, true, node)
+	ml.recordTransitionLocked(existing, true, node)
+	if !IsTombstone(node.Status) {
+		ml.promoteBootstrapLocked(node)
+	}
 
-	// 1. Se ricevo info più recente, aggiorna sempre
-	if newTime.After(existingTime) {
-		ml.members[node.ID] = node
+	// ✅ Solo i cambiamenti di stato diventano rumour (gli heartbeat viaggiano col push-pull)
+	if node.Status != existing.Status || node.Incarnation != existing.Incarnation {
+		ml.rumours.enqueue(node)
+	}
+}
+
+// ✅ Salva l'entry di un nodo mantenendo StatusSince, l'istante LOCALE in cui è entrato
+// nello stato corrente: da lì si misurano il timeout dei SUSPECT e la durata dei tombstone
+// (il chiamante deve possedere il lock)
+func (ml *MembershipList) storeLocked(previous util.NodeStatus, existed bool, node util.NodeStatus) {
+	if existed && previous.Status == node.Status {
+		node.StatusSince = previous.StatusSince
+	} else {
+		node.StatusSince = ml.clock.Now().Format(time.RFC3339Nano)
+	}
+	ml.members[node.ID] = node
+}
+
+// ✅ Confuta un'accusa sul nodo locale incrementando la propria incarnation
+// L'entry ALIVE con incarnation più alta prevale su SUSPECT/DEAD in tutto il cluster.
+// Vale anche per le entry di una vita precedente del nodo (riavvio, magari con un altro
+// indirizzo): la nuova incarnation sostituisce la vecchia entry invece di duplicarla
+func (ml *MembershipList) refuteLocked(self, accusation util.NodeStatus) {
+	// Dopo il LEAVE il nodo non si difende più: il suo tombstone deve restare tale
+	if self.Status == "left" || accusation.Incarnation < self.Incarnation {
 		return
 	}
 
-	// 2. Se stesso timestamp, usa la precedenza degli stati
-	if newTime.Equal(existingTime) {
-		// Precedenza: ALIVE > SUSPECT > DEAD
-		if shouldUpdateState(existing.Status, node.Status) {
-			existing.Status = node.Status
-			ml.members[node.ID] = existing
-		}
+	staleAddress := accusation.Address() != self.Address()
+	staleHeartbeat := accusation.Incarnation == self.Incarnation && accusation.Heartbeat > self.Heartbeat
+	if accusation.Status == "alive" && !staleAddress && !staleHeartbeat {
 		return
 	}
+	if staleAddress {
+		util.Warn(fmt.Sprintf("[MEMBERSHIP] Ricevuta entry di %s con indirizzo %s (locale %s): annuncio nuova incarnation", self.ID, accusation.Address(), self.Address()))
+	}
 
-	// 3. Se ricevo info più vecchia, aggiorna solo se è "migliore"
-	if newTime.Before(existingTime) {
-		// Solo se lo stato ricevuto è "migliore" (es. ALIVE vs SUSPECT)
-		if shouldUpdateState(existing.Status, node.Status) {
-			// Mantieni il timestamp più recente ma aggiorna lo stato
-			existing.Status = node.Status
-			ml.members[node.ID] = existing
-		}
+	previous := self
+	self.Incarnation = accusation.Incarnation + 1
+	self.Status = "alive"
+	self = ml.signLocked(self)
+	ml.storeLocked(previous, true, self)
+	ml.rumours.enqueue(self)
+	ml.recordTransitionLocked(previous, true, self)
+
+	// Notifica non bloccante: basta un segnale anche per più accuse ravvicinate
+	select {
+	case ml.refuteCh <- struct{}{}:
+	default:
 	}
 }
 
-func shouldUpdateState(currentStatus, newStatus string) bool {
-	// Mappa priorità stati: ALIVE > SUSPECT > DEAD
-	priority := map[string]int{
-		"alive":   3,
-		"suspect": 2,
-		"dead":    1,
-	}
+// ✅ Entry corrente del nodo locale (indirizzo e incarnation aggiornati), da usare come mittente
+func (ml *MembershipList) Self() util.NodeStatus {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	self := ml.members[ml.selfID]
+	self.LastSeen = ""
+	self.StatusSince = ""
+	return self
+}
 
-	currentPrio, okCurrent := priority[currentStatus]
-	newPrio, okNew := priority[newStatus]
+// ✅ Cambia l'indirizzo annunciato dal nodo locale (es. scoperto durante il JOIN).
+// La nuova incarnation fa prevalere il nuovo indirizzo in tutto il cluster
+func (ml *MembershipList) UpdateSelfAddress(ip, port string) bool {
+	ml.mutex.Lock()
+	defer ml.unlockAndNotify()
 
-	if !okCurrent || !okNew {
+	self, exists := ml.members[ml.selfID]
+	if !exists || self.Status == "left" || (self.IP == ip && self.Port == port) {
 		return false
 	}
 
-	// Aggiorna solo se il nuovo stato ha priorità maggiore (è "migliore")
-	return newPrio > currentPrio
+	previous := self
+	self.IP = ip
+	self.Port = port
+	self.Incarnation++
+	self = ml.signLocked(self)
+	ml.storeLocked(previous, true, self)
+	ml.rumours.enqueue(self)
+	ml.recordTransitionLocked(previous, true, self)
+
+	// Come per la confutazione: il ciclo di gossip annuncia subito la nuova entry
+	select {
+	case ml.refuteCh <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// ✅ Marca il nodo locale come LEFT e restituisce la entry (firmata se le identità sono attive)
+// da allegare al LEAVE. Da qui in poi il nodo non confuta più le entry su di sé e non
+// incrementa l'heartbeat: il proprio LEFT che torna via gossip non lo fa rientrare nel cluster
+func (ml *MembershipList) MarkSelfLeft() util.NodeStatus {
+	ml.mutex.Lock()
+	defer ml.unlockAndNotify()
+
+	self := ml.members[ml.selfID]
+	if self.Status != "left" {
+		previous := self
+		self.Status = "left"
+		self = ml.signLocked(self)
+		ml.storeLocked(previous, true, self)
+		ml.recordTransitionLocked(previous, true, self)
+	}
+
+	self.LastSeen = ""
+	self.StatusSince = ""
+	return self
+}
+
+// ✅ Indica se il nodo locale ha lasciato il cluster (vedi MarkSelfLeft)
+func (ml *MembershipList) HasLeft() bool {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	return ml.members[ml.selfID].Status == "left"
+}
+
+// ✅ Canale che segnala quando il nodo locale ha confutato un sospetto e deve annunciarsi ALIVE
+func (ml *MembershipList) Refutations() <-chan struct{} {
+	return ml.refuteCh
+}
+
+// ✅ REGOLE per risolvere conflitti di stato (indipendenti dal clock):
+//  1. Incarnation più alta vince sempre
+//  2. A parità di incarnation, un tombstone (DEAD/LEFT) vince su qualsiasi heartbeat:
+//     solo il nodo stesso può tornare ALIVE incrementando la propria incarnation
+//  3. A parità di incarnation, heartbeat più alto vince
+//  4. A parità di entrambi, vince lo stato più "avanzato" (LEFT > DEAD > SUSPECT > ALIVE)
+func isNewer(existing, received util.NodeStatus) bool {
+	if received.Incarnation != existing.Incarnation {
+		return received.Incarnation > existing.Incarnation
+	}
+	if IsTombstone(existing.Status) || IsTombstone(received.Status) {
+		return statePriority(received.Status) > statePriority(existing.Status)
+	}
+	if received.Heartbeat != existing.Heartbeat {
+		return received.Heartbeat > existing.Heartbeat
+	}
+	return statePriority(received.Status) > statePriority(existing.Status)
+}
+
+func statePriority(status string) int {
+	// Mappa priorità stati a parità di versione: LEFT > DEAD > SUSPECT > ALIVE
+	priority := map[string]int{
+		"alive":   1,
+		"suspect": 2,
+		"dead":    3,
+		"left":    4,
+	}
+	return priority[status]
+}
+
+// ✅ Indica se lo stato è un tombstone (nodo morto o uscito volontariamente)
+func IsTombstone(status string) bool {
+	return status == "dead" || status == "left"
 }
 
 // ✅ Rimuove un nodo dalla lista
 func (ml *MembershipList) RemoveNode(nodeID string) {
 	ml.mutex.Lock()
-	defer ml.mutex.Unlock()
+	defer ml.unlockAndNotify()
 
-	delete(ml.members, nodeID)
+	if node, exists := ml.members[nodeID]; exists {
+		delete(ml.members, nodeID)
+		ml.recordRemovalLocked(node)
+	}
 }
 
 // ✅ Marca un nodo come SUSPECT
 func (ml *MembershipList) MarkNodeSuspect(nodeID string) {
 	ml.mutex.Lock()
-	defer ml.mutex.Unlock()
+	defer ml.unlockAndNotify()
 
-	if node, exists := ml.members[nodeID]; exists && node.Status != "dead" {
+	if node, exists := ml.members[nodeID]; exists && !IsTombstone(node.Status) {
+		previous := node
 		node.Status = "suspect"
-		ml.members[nodeID] = node
+		ml.storeLocked(previous, true, node)
+		ml.rumours.enqueue(node)
+		ml.recordTransitionLocked(previous, true, node)
 	}
 }
 
-// ✅ Marca un nodo come DEAD
+// ✅ Marca un nodo come DEAD (tombstone)
 func (ml *MembershipList) MarkNodeDead(nodeID string) {
 	ml.mutex.Lock()
-	defer ml.mutex.Unlock()
+	defer ml.unlockAndNotify()
 
-	if node, exists := ml.members[nodeID]; exists {
+	if node, exists := ml.members[nodeID]; exists && node.Status != "left" {
+		previous := node
 		node.Status = "dead"
-		ml.members[nodeID] = node
+		ml.storeLocked(previous, true, node)
+		ml.rumours.enqueue(node)
+		ml.recordTransitionLocked(previous, true, node)
 	}
 }
 
@@ -136,6 +396,15 @@ func (ml *MembershipList) GetCopy() []util.NodeStatus {
 	return list
 }
 
+// ✅ Restituisce una copia dello stato di un nodo specifico
+func (ml *MembershipList) GetNode(nodeID string) (util.NodeStatus, bool) {
+	ml.mutex.RLock()
+	defer ml.mutex.RUnlock()
+
+	node, exists := ml.members[nodeID]
+	return node, exists
+}
+
 // ✅ Restituisce il timestamp "LastSeen" per un nodo specifico (utile per Failure Detection)
 func (ml *MembershipList) GetLastSeen(nodeID string) (string, bool) {
 	ml.mutex.RLock()
@@ -161,32 +430,61 @@ func (ml *MembershipList) GetNodeStatus(nodeID string) (string, bool) {
 }
 
 // ✅ Aggiorna il timestamp "LastSeen" di un nodo (per heartbeat implicito via Gossip Update)
+// Lo stato non cambia: un SUSPECT torna ALIVE solo confutando il sospetto con un'incarnation
+// più alta annunciata da lui stesso (vedi refuteLocked), altrimenti nodi diversi oscillerebbero
+// tra ALIVE e SUSPECT generando eventi duplicati
 func (ml *MembershipList) UpdateLastSeen(nodeID string) {
 	ml.mutex.Lock()
 	defer ml.mutex.Unlock()
 
-	if node, exists := ml.members[nodeID]; exists && node.Status != "dead" {
-		node.LastSeen = time.Now().Format(time.RFC3339)
-		node.Status = "alive" // Se ricevo da lui, lo considero vivo
+	if node, exists := ml.members[nodeID]; exists && !IsTombstone(node.Status) {
+		node.LastSeen = ml.clock.Now().Format(time.RFC3339Nano)
 		ml.members[nodeID] = node
 	}
 }
 
+// ✅ Incrementa il contatore heartbeat del proprio nodo (da chiamare solo per sé stessi)
+func (ml *MembershipList) IncrementHeartbeat(nodeID string) {
+	ml.mutex.Lock()
+	defer ml.unlockAndNotify()
+
+	if node, exists := ml.members[nodeID]; exists && node.Status != "left" {
+		previous := node
+		node.Heartbeat++
+		node.Status = "alive"
+		node.LastSeen = ml.clock.Now().Format(time.RFC3339Nano)
+		if nodeID == ml.selfID {
+			node = ml.signLocked(node)
+		}
+		ml.storeLocked(previous, true, node)
+		ml.recordTransitionLocked(previous, true, node)
+	}
+}
+
 // ✅ Merge della Membership List ricevuta con quella locale
 func (ml *MembershipList) MergeMembership(receivedList []util.NodeStatus) {
 	ml.mutex.Lock()
-	defer ml.mutex.Unlock()
+	defer ml.unlockAndNotify()
 
 	for _, receivedNode := range receivedList {
-		existingNode, exists := ml.members[receivedNode.ID]
-
-		// Se il nodo non esiste, o se il LastSeen ricevuto è più recente, aggiorniamo
-		if !exists || receivedNode.LastSeen > existingNode.LastSeen {
-			ml.members[receivedNode.ID] = receivedNode
-		}
+		ml.addOrUpdateLocked(receivedNode)
 	}
 }
 
+// ✅ Restituisce i rumour da allegare al prossimo messaggio in uscita (al massimo limit)
+func (ml *MembershipList) GetRumours(limit int) []util.NodeStatus {
+	ml.mutex.RLock()
+	numNodes := len(ml.members)
+	ml.mutex.RUnlock()
+
+	return ml.rumours.get(limit, numNodes)
+}
+
+// ✅ Numero di rumour ancora in attesa di diffusione
+func (ml *MembershipList) PendingRumours() int {
+	return ml.rumours.len()
+}
+
 // ✅ Ritorna l'intero contenuto della mappa (per Debug)
 func (ml *MembershipList) Print() map[string]util.NodeStatus {
 	ml.mutex.RLock()
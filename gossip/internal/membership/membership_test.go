@@ -0,0 +1,425 @@
+package membership
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/util"
+)
+
+// ✅ Membership List con il solo nodo locale "self", su orologio virtuale.
+// Le entry LEFT ricevute sono considerate autentiche (vedi TestUntrustedLeftRejected)
+func newTestList() *MembershipList {
+	ml := NewMembershipList("self", clock.NewVirtual(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	ml.SetTrustedClaims(true)
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "self", IP: "10.0.0.1", Port: "9000", Status: "alive"})
+	return ml
+}
+
+func TestPromoteBootstrapNode(t *testing.T) {
+	seed := util.NodeStatus{ID: "seed", IP: "10.0.0.2", Port: "9000", Status: "alive"}
+
+	tests := []struct {
+		name     string
+		node     util.NodeStatus
+		source   string
+		promoted bool
+	}{
+		{"seed dal proprio indirizzo", seed, "10.0.0.2:9000", true},
+		{"seed da un'altra porta", seed, "10.0.0.2:40000", false},
+		{"seed da un altro host", seed, "10.0.0.9:9000", false},
+		{"indirizzo che non è un seed", util.NodeStatus{ID: "other", IP: "10.0.0.3", Port: "9000", Status: "alive"}, "10.0.0.3:9000", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ml := newTestList()
+			ml.AddBootstrapAddresses([]string{"10.0.0.2:9000"})
+
+			if got := ml.PromoteBootstrapNode(test.node, test.source); got != test.promoted {
+				t.Fatalf("PromoteBootstrapNode = %v, atteso %v", got, test.promoted)
+			}
+			if _, exists := ml.GetNode(test.node.ID); exists != test.promoted {
+				t.Fatalf("nodo presente = %v, atteso %v", exists, test.promoted)
+			}
+			if pending := len(ml.BootstrapAddresses()); (pending == 0) != test.promoted {
+				t.Fatalf("seed ancora da verificare: %d", pending)
+			}
+		})
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	entry := func(status string, incarnation, heartbeat uint64) util.NodeStatus {
+		return util.NodeStatus{ID: "peer", Status: status, Incarnation: incarnation, Heartbeat: heartbeat}
+	}
+
+	tests := []struct {
+		name     string
+		existing util.NodeStatus
+		received util.NodeStatus
+		newer    bool
+	}{
+		{"incarnation più alta vince", entry("dead", 1, 50), entry("alive", 2, 0), true},
+		{"incarnation più bassa perde", entry("alive", 2, 0), entry("left", 1, 50), false},
+		{"heartbeat più alto vince", entry("alive", 1, 5), entry("alive", 1, 6), true},
+		{"heartbeat più basso perde", entry("suspect", 1, 6), entry("alive", 1, 5), false},
+		{"a parità vince suspect su alive", entry("alive", 1, 5), entry("suspect", 1, 5), true},
+		{"a parità alive non batte suspect", entry("suspect", 1, 5), entry("alive", 1, 5), false},
+		{"entry identica non è più nuova", entry("alive", 1, 5), entry("alive", 1, 5), false},
+		{"tombstone vince su heartbeat più alto", entry("dead", 1, 5), entry("alive", 1, 99), false},
+		{"tombstone batte alive con heartbeat più alto", entry("alive", 1, 99), entry("dead", 1, 5), true},
+		{"left batte dead", entry("dead", 1, 5), entry("left", 1, 5), true},
+		{"dead non batte left", entry("left", 1, 5), entry("dead", 1, 9), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isNewer(test.existing, test.received); got != test.newer {
+				t.Fatalf("isNewer = %v, atteso %v", got, test.newer)
+			}
+		})
+	}
+}
+
+func TestRefuteAccusations(t *testing.T) {
+	tests := []struct {
+		name        string
+		accusation  util.NodeStatus
+		refuted     bool
+		incarnation uint64
+	}{
+		{"suspect alla stessa incarnation", util.NodeStatus{Status: "suspect", Incarnation: 3}, true, 4},
+		{"dead con incarnation più alta", util.NodeStatus{Status: "dead", Incarnation: 7}, true, 8},
+		{"accusa obsoleta", util.NodeStatus{Status: "dead", Incarnation: 2}, false, 3},
+		{"alive coerente", util.NodeStatus{Status: "alive", Incarnation: 3}, false, 3},
+		{"alive con un altro indirizzo", util.NodeStatus{Status: "alive", Incarnation: 3, Port: "9999"}, true, 4},
+		{"alive con heartbeat più alto", util.NodeStatus{Status: "alive", Incarnation: 3, Heartbeat: 100}, true, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ml := newTestList()
+			self := ml.Self()
+			self.Incarnation = 3
+			ml.members["self"] = self
+
+			accusation := test.accusation
+			accusation.ID, accusation.IP = "self", "10.0.0.1"
+			if accusation.Port == "" {
+				accusation.Port = "9000"
+			}
+			ml.AddOrUpdateNode(accusation)
+
+			got := ml.Self()
+			if got.Status != "alive" || got.Incarnation != test.incarnation {
+				t.Fatalf("nodo locale %s/%d, atteso alive/%d", got.Status, got.Incarnation, test.incarnation)
+			}
+			if got.Port != "9000" {
+				t.Fatalf("indirizzo locale sovrascritto: %s", got.Address())
+			}
+			select {
+			case <-ml.Refutations():
+				if !test.refuted {
+					t.Fatal("confutazione inattesa")
+				}
+			default:
+				if test.refuted {
+					t.Fatal("accusa non confutata")
+				}
+			}
+		})
+	}
+}
+
+func TestLeftSelfIsNotRefuted(t *testing.T) {
+	ml := newTestList()
+	left := ml.MarkSelfLeft()
+
+	// Il proprio LEFT (o un'accusa) che torna via gossip non fa rientrare il nodo
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "self", IP: "10.0.0.1", Port: "9000", Status: "dead", Incarnation: left.Incarnation})
+	ml.IncrementHeartbeat("self")
+	if got := ml.Self(); got.Status != "left" || got.Incarnation != left.Incarnation {
+		t.Fatalf("nodo uscito: %s/%d, atteso left/%d", got.Status, got.Incarnation, left.Incarnation)
+	}
+	if !ml.HasLeft() {
+		t.Fatal("HasLeft = false dopo MarkSelfLeft")
+	}
+}
+
+func TestTombstones(t *testing.T) {
+	alive := util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "alive", Incarnation: 1, Heartbeat: 10}
+	with := func(status string, incarnation, heartbeat uint64) util.NodeStatus {
+		node := alive
+		node.Status, node.Incarnation, node.Heartbeat = status, incarnation, heartbeat
+		return node
+	}
+
+	tests := []struct {
+		name    string
+		updates []util.NodeStatus
+		status  string // "" = nodo assente
+	}{
+		{"tombstone di un nodo sconosciuto ignorato", []util.NodeStatus{with("dead", 1, 10)}, ""},
+		{"left sconosciuto ignorato", []util.NodeStatus{with("left", 1, 10)}, ""},
+		{"dead applicato", []util.NodeStatus{alive, with("dead", 1, 10)}, "dead"},
+		{"alive obsoleto non resuscita il tombstone", []util.NodeStatus{alive, with("dead", 1, 10), with("alive", 1, 50)}, "dead"},
+		{"incarnation più alta resuscita il tombstone", []util.NodeStatus{alive, with("dead", 1, 10), with("alive", 2, 0)}, "alive"},
+		{"left prevale su dead", []util.NodeStatus{alive, with("dead", 1, 10), with("left", 1, 10)}, "left"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ml := newTestList()
+			ml.MergeMembership(test.updates)
+
+			status, _ := ml.GetNodeStatus("peer")
+			if status != test.status {
+				t.Fatalf("stato %q, atteso %q", status, test.status)
+			}
+		})
+	}
+}
+
+func TestUntrustedLeftRejected(t *testing.T) {
+	ml := newTestList()
+	ml.SetTrustedClaims(false)
+	peer := util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "alive", Incarnation: 1}
+	left := peer
+	left.Status = "left"
+
+	// Senza identità né keyring una entry LEFT può venire da chiunque: viene scartata
+	ml.MergeMembership([]util.NodeStatus{peer, left})
+	if status, _ := ml.GetNodeStatus("peer"); status != "alive" {
+		t.Fatalf("stato %q, atteso alive", status)
+	}
+	if ml.RejectedEntries() != 1 {
+		t.Fatalf("entry rifiutate: %d, attesa 1", ml.RejectedEntries())
+	}
+	if ml.SelfClaimsAuthenticated() {
+		t.Fatal("SelfClaimsAuthenticated = true senza identità né keyring")
+	}
+
+	ml.SetTrustedClaims(true)
+	ml.AddOrUpdateNode(left)
+	if status, _ := ml.GetNodeStatus("peer"); status != "left" {
+		t.Fatalf("stato %q, atteso left", status)
+	}
+}
+
+func TestTombstoneIgnoresLocalMarks(t *testing.T) {
+	ml := newTestList()
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "left"})
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "alive"})
+	ml.AddOrUpdateNode(util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "left"})
+
+	// Un tombstone LEFT non torna SUSPECT/DEAD e non viene "rianimato" da UpdateLastSeen
+	ml.MarkNodeSuspect("peer")
+	ml.MarkNodeDead("peer")
+	ml.UpdateLastSeen("peer")
+	if status, _ := ml.GetNodeStatus("peer"); status != "left" {
+		t.Fatalf("stato %q, atteso left", status)
+	}
+}
+
+func TestRumourQueue(t *testing.T) {
+	node := func(id string, incarnation uint64) util.NodeStatus {
+		return util.NodeStatus{ID: id, Status: "alive", Incarnation: incarnation, LastSeen: "locale"}
+	}
+
+	t.Run("un solo rumour per nodo, il più recente", func(t *testing.T) {
+		q := newRumourQueue()
+		q.enqueue(node("a", 1))
+		q.enqueue(node("a", 2))
+		got := q.get(MaxPiggybackRumours, 3)
+		if len(got) != 1 || got[0].Incarnation != 2 {
+			t.Fatalf("rumour %+v, atteso solo a/2", got)
+		}
+		if got[0].LastSeen != "" {
+			t.Fatalf("LastSeen locale trasmesso: %q", got[0].LastSeen)
+		}
+	})
+
+	t.Run("limite per messaggio e priorità ai meno trasmessi", func(t *testing.T) {
+		q := newRumourQueue()
+		q.enqueue(node("a", 1))
+		q.enqueue(node("b", 1))
+		first := q.get(1, 3)
+		if len(first) != 1 {
+			t.Fatalf("%d rumour con limit 1", len(first))
+		}
+		// Il secondo messaggio porta il rumour non ancora trasmesso
+		second := q.get(1, 3)
+		if len(second) != 1 || second[0].ID == first[0].ID {
+			t.Fatalf("rumour %+v dopo %+v", second, first)
+		}
+	})
+
+	t.Run("scartato dopo λ·log(N) trasmissioni", func(t *testing.T) {
+		for _, numNodes := range []int{1, 9, 10, 100} {
+			q := newRumourQueue()
+			q.enqueue(node("a", 1))
+			limit := retransmitLimit(numNodes)
+			for i := 0; i < limit; i++ {
+				if got := q.get(MaxPiggybackRumours, numNodes); len(got) != 1 {
+					t.Fatalf("N=%d: trasmissione %d senza rumour", numNodes, i+1)
+				}
+			}
+			if q.len() != 0 || len(q.get(MaxPiggybackRumours, numNodes)) != 0 {
+				t.Fatalf("N=%d: rumour ancora presente dopo %d trasmissioni", numNodes, limit)
+			}
+		}
+	})
+}
+
+func TestTransitionKind(t *testing.T) {
+	base := util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "alive", Incarnation: 1, Heartbeat: 1}
+	with := func(change func(*util.NodeStatus)) util.NodeStatus {
+		node := base
+		change(&node)
+		return node
+	}
+
+	tests := []struct {
+		name     string
+		previous util.NodeStatus
+		existed  bool
+		current  util.NodeStatus
+		kind     eventKind
+		changed  bool
+	}{
+		{"nodo nuovo", util.NodeStatus{}, false, base, eventJoin, true},
+		{"solo heartbeat", base, true, with(func(n *util.NodeStatus) { n.Heartbeat = 9 }), 0, false},
+		{"sospetto", base, true, with(func(n *util.NodeStatus) { n.Status = "suspect" }), eventSuspect, true},
+		{"morto", base, true, with(func(n *util.NodeStatus) { n.Status = "dead" }), eventDead, true},
+		{"uscito", base, true, with(func(n *util.NodeStatus) { n.Status = "left" }), eventLeave, true},
+		{"sospetto confutato", with(func(n *util.NodeStatus) { n.Status = "suspect" }), true, with(func(n *util.NodeStatus) { n.Incarnation = 2 }), eventUpdate, true},
+		{"nuova incarnation", base, true, with(func(n *util.NodeStatus) { n.Incarnation = 2 }), eventUpdate, true},
+		{"nuovo indirizzo", base, true, with(func(n *util.NodeStatus) { n.Port = "9001" }), eventUpdate, true},
+		{"ritorno da tombstone", with(func(n *util.NodeStatus) { n.Status = "dead" }), true, with(func(n *util.NodeStatus) { n.Incarnation = 2 }), eventJoin, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kind, changed := transitionKind(test.previous, test.existed, test.current)
+			if changed != test.changed || (changed && kind != test.kind) {
+				t.Fatalf("transitionKind = (%v, %v), atteso (%v, %v)", kind, changed, test.kind, test.changed)
+			}
+		})
+	}
+}
+
+// ✅ Delegate che registra gli eventi ricevuti come "tipo:id"
+type recordingDelegate struct {
+	events []string
+}
+
+func (d *recordingDelegate) NotifyJoin(node util.NodeStatus)    { d.record("join", node) }
+func (d *recordingDelegate) NotifyUpdate(node util.NodeStatus)  { d.record("update", node) }
+func (d *recordingDelegate) NotifySuspect(node util.NodeStatus) { d.record("suspect", node) }
+func (d *recordingDelegate) NotifyLeave(node util.NodeStatus)   { d.record("leave", node) }
+func (d *recordingDelegate) NotifyDead(node util.NodeStatus)    { d.record("dead", node) }
+
+func (d *recordingDelegate) record(kind string, node util.NodeStatus) {
+	d.events = append(d.events, kind+":"+node.ID)
+}
+
+func TestEventsFireExactlyOnce(t *testing.T) {
+	ml := newTestList()
+	delegate := &recordingDelegate{}
+	ml.SetEventDelegate(delegate)
+
+	peer := util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "alive", Incarnation: 1}
+	suspect := peer
+	suspect.Status = "suspect"
+	refuted := peer
+	refuted.Incarnation = 2
+	dead := refuted
+	dead.Status = "dead"
+
+	// Ogni cambiamento arriva più volte (rumour da più nodi, push-pull, probe locali)
+	for i := 0; i < 3; i++ {
+		ml.AddOrUpdateNode(peer)
+		ml.UpdateLastSeen("peer")
+	}
+	for i := 0; i < 3; i++ {
+		ml.MergeMembership([]util.NodeStatus{suspect, peer})
+		ml.MarkNodeSuspect("peer")
+		ml.UpdateLastSeen("peer")
+	}
+	for i := 0; i < 3; i++ {
+		ml.AddOrUpdateNode(refuted)
+		ml.UpdateLastSeen("peer")
+	}
+	for i := 0; i < 3; i++ {
+		ml.AddOrUpdateNode(dead)
+		ml.MarkNodeDead("peer")
+		ml.AddOrUpdateNode(refuted) // ALIVE obsoleto: il tombstone resta
+	}
+	ml.RemoveNode("peer")
+
+	want := []string{"join:peer", "suspect:peer", "update:peer", "dead:peer"}
+	if len(delegate.events) != len(want) {
+		t.Fatalf("eventi %v, attesi %v", delegate.events, want)
+	}
+	for i := range want {
+		if delegate.events[i] != want[i] {
+			t.Fatalf("eventi %v, attesi %v", delegate.events, want)
+		}
+	}
+}
+
+// ✅ Delegate che verifica l'ordine di consegna: incarnation crescenti e nessuna chiamata concorrente
+type orderingDelegate struct {
+	recordingDelegate
+	active     atomic.Int32
+	last       uint64
+	violations []string
+}
+
+func (d *orderingDelegate) NotifyJoin(node util.NodeStatus)   { d.check(node) }
+func (d *orderingDelegate) NotifyUpdate(node util.NodeStatus) { d.check(node) }
+
+func (d *orderingDelegate) check(node util.NodeStatus) {
+	if d.active.Add(1) != 1 {
+		d.violations = append(d.violations, "consegna concorrente")
+	}
+	defer d.active.Add(-1)
+
+	// Lascia spazio alle altre goroutine, come farebbe un delegate reale
+	runtime.Gosched()
+	if node.Incarnation <= d.last {
+		d.violations = append(d.violations, fmt.Sprintf("incarnation %d dopo %d", node.Incarnation, d.last))
+	}
+	d.last = node.Incarnation
+}
+
+func TestEventsDeliveredInOrder(t *testing.T) {
+	ml := newTestList()
+	delegate := &orderingDelegate{}
+	ml.SetEventDelegate(delegate)
+
+	// Più goroutine applicano incarnation crescenti dello stesso nodo: la lista scarta quelle
+	// arrivate dopo una più recente, e il delegate deve vedere le altre nello stesso ordine
+	const workers, updates = 8, 200
+	var next atomic.Uint64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				incarnation := next.Add(1)
+				ml.AddOrUpdateNode(util.NodeStatus{ID: "peer", IP: "10.0.0.2", Port: "9000", Status: "alive", Incarnation: incarnation})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(delegate.violations) > 0 {
+		t.Fatalf("%d consegne fuori ordine, es. %s", len(delegate.violations), delegate.violations[0])
+	}
+	if delegate.last != workers*updates {
+		t.Fatalf("ultima incarnation consegnata %d, attesa %d", delegate.last, workers*updates)
+	}
+}
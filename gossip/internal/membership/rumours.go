@@ -0,0 +1,89 @@
+package membership
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"Gossip/internal/util"
+)
+
+const (
+	RetransmitMult      = 4  // λ: ogni rumour viene ritrasmesso λ·log(N) volte
+	MaxPiggybackRumours = 10 // Numero massimo di rumour allegati a un singolo messaggio
+)
+
+// ✅ Rumour: cambiamento di stato di un nodo da diffondere via piggyback
+type rumour struct {
+	node      util.NodeStatus
+	transmits int // Quante volte è già stato trasmesso
+}
+
+// ✅ Coda dei rumour con ritrasmissione limitata
+// Per ogni nodo si tiene solo il rumour più recente: un nuovo cambiamento sostituisce il precedente
+type rumourQueue struct {
+	mutex   sync.Mutex
+	rumours map[string]*rumour // mappa da ID nodo al rumour più recente
+}
+
+func newRumourQueue() *rumourQueue {
+	return &rumourQueue{
+		rumours: make(map[string]*rumour),
+	}
+}
+
+// ✅ Accoda (o sostituisce) il rumour relativo a un nodo, azzerando le trasmissioni
+func (q *rumourQueue) enqueue(node util.NodeStatus) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	node.LastSeen = ""
+	node.StatusSince = ""
+	q.rumours[node.ID] = &rumour{node: node}
+}
+
+// ✅ Estrae fino a limit rumour (prima i meno trasmessi) e scarta quelli che hanno
+// raggiunto il limite di ritrasmissioni λ·log(N)
+func (q *rumourQueue) get(limit, numNodes int) []util.NodeStatus {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.rumours) == 0 {
+		return nil
+	}
+
+	pending := make([]*rumour, 0, len(q.rumours))
+	for _, r := range q.rumours {
+		pending = append(pending, r)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].transmits < pending[j].transmits
+	})
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	maxTransmits := retransmitLimit(numNodes)
+	nodes := make([]util.NodeStatus, 0, len(pending))
+	for _, r := range pending {
+		nodes = append(nodes, r.node)
+		r.transmits++
+		if r.transmits >= maxTransmits {
+			delete(q.rumours, r.node.ID)
+		}
+	}
+	return nodes
+}
+
+// ✅ Numero di rumour ancora da diffondere
+func (q *rumourQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.rumours)
+}
+
+// ✅ Limite di ritrasmissioni: λ·ceil(log10(N+1))
+func retransmitLimit(numNodes int) int {
+	return RetransmitMult * int(math.Ceil(math.Log10(float64(numNodes+1))))
+}
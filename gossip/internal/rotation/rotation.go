@@ -0,0 +1,256 @@
+// Package rotation propaga a tutti i membri del cluster le operazioni sul keyring
+// (install, use, remove, list), così una chiave può essere ruotata senza riavviare i nodi.
+//
+// Una rotazione sicura procede in tre passi, ciascuno eseguito su tutto il cluster:
+// install della nuova chiave, use (diventa primaria), remove della vecchia. Remove viene
+// rifiutata finché anche un solo membro non conferma di usare la stessa chiave primaria
+// del nodo che la esegue: un membro irraggiungibile al momento di use non resta isolato.
+package rotation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/keyring"
+	"Gossip/internal/membership"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// Operazioni sul keyring
+const (
+	OpInstall = "install" // Installa una chiave accettata in decifratura
+	OpUse     = "use"     // Rende primaria una chiave installata
+	OpRemove  = "remove"  // Rimuove una chiave non primaria
+	OpList    = "list"    // Elenca le chiavi installate
+)
+
+// Tempo massimo di risposta di un singolo membro, tentativi e attesa tra un tentativo e l'altro
+const (
+	memberTimeout  = 5 * time.Second
+	memberAttempts = 3
+	memberBackoff  = 1 * time.Second
+)
+
+// ✅ Manager esegue le operazioni sul keyring locale e le propaga agli altri membri
+type Manager struct {
+	keyring         *keyring.Keyring
+	transport       transport.Transport
+	localMembership *membership.MembershipList
+	clock           clock.Clock // Orologio del timeout di ciascun membro
+}
+
+// Costruttore: crea il Manager del nodo locale (il transport deve essere quello cifrato con k)
+func NewManager(k *keyring.Keyring, nodeTransport transport.Transport, localMembership *membership.MembershipList, clk clock.Clock) *Manager {
+	return &Manager{
+		keyring:         k,
+		transport:       nodeTransport,
+		localMembership: localMembership,
+		clock:           clk,
+	}
+}
+
+// ✅ Esegue l'operazione su tutti i membri attivi (ALIVE e SUSPECT) e restituisce l'esito per nodo.
+// Il nodo locale applica l'operazione per ultimo: le richieste agli altri membri partono
+// cifrate con la chiave primaria precedente all'operazione
+func (m *Manager) Execute(ctx context.Context, op string, key []byte) map[string]util.KeyResult {
+	self := m.localMembership.Self()
+	request := util.KeyMessage{
+		Type:   "key_op",
+		Op:     op,
+		Sender: self.ID,
+	}
+	if key != nil {
+		request.Key = base64.StdEncoding.EncodeToString(key)
+	}
+
+	if op == OpRemove {
+		if results, ok := m.checkRemove(ctx, self.ID); !ok {
+			return results
+		}
+	}
+
+	results := m.broadcast(ctx, self.ID, request)
+	results[self.ID] = m.Apply(op, request.Key, self.ID)
+	return results
+}
+
+// ✅ Prima di remove ogni membro deve confermare di usare la primaria locale: chi non l'ha
+// ricevuta con use (o non risponde) non potrebbe più decifrare il traffico dopo la rimozione.
+// Se un membro non conferma, remove non viene eseguita su nessun nodo
+func (m *Manager) checkRemove(ctx context.Context, selfID string) (map[string]util.KeyResult, bool) {
+	primary := base64.StdEncoding.EncodeToString(m.keyring.PrimaryKey())
+	results := m.broadcast(ctx, selfID, util.KeyMessage{Type: "key_op", Op: OpList, Sender: selfID})
+
+	pending := 0
+	for id, result := range results {
+		switch {
+		case result.Error != "":
+			result.Error = fmt.Sprintf("remove rifiutata, membro non verificato: %s", result.Error)
+		case len(result.Keys) == 0 || result.Keys[0] != primary:
+			result.Error = "remove rifiutata: il membro non usa ancora la chiave primaria (ripetere use)"
+		default:
+			continue
+		}
+		results[id] = result
+		pending++
+	}
+	if pending == 0 {
+		return nil, true
+	}
+
+	for id, result := range results {
+		if result.Error == "" {
+			result.Error = fmt.Sprintf("remove non eseguita: %d membri non usano la chiave primaria", pending)
+			results[id] = result
+		}
+	}
+	results[selfID] = util.KeyResult{
+		Keys:  EncodeKeys(m.keyring.Keys()),
+		Error: fmt.Sprintf("remove non eseguita: %d membri non usano la chiave primaria", pending),
+	}
+	util.Warn(fmt.Sprintf("[KEYRING] Remove rifiutata: %d membri non confermano la chiave primaria", pending))
+	return results, false
+}
+
+// ✅ Invia la richiesta in parallelo a tutti i membri attivi tranne il nodo locale
+func (m *Manager) broadcast(ctx context.Context, selfID string, request util.KeyMessage) map[string]util.KeyResult {
+	results := make(map[string]util.KeyResult)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, node := range m.localMembership.GetCopy() {
+		if node.ID == selfID || membership.IsTombstone(node.Status) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(node util.NodeStatus) {
+			defer wg.Done()
+			result := m.sendKeyOp(ctx, node, request)
+
+			mutex.Lock()
+			results[node.ID] = result
+			mutex.Unlock()
+		}(node)
+	}
+	wg.Wait()
+	return results
+}
+
+// ✅ Invia l'operazione a un membro e ne attende l'esito, ritentando se non risponde
+// (install, use e list sono idempotenti)
+func (m *Manager) sendKeyOp(ctx context.Context, node util.NodeStatus, request util.KeyMessage) util.KeyResult {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return util.KeyResult{Error: err.Error()}
+	}
+
+	var responseData []byte
+	for attempt := 1; ; attempt++ {
+		responseData, err = m.exchange(ctx, node, data)
+		if err == nil || attempt == memberAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return util.KeyResult{Error: fmt.Sprintf("nessuna risposta: %v", err)}
+		case <-m.clock.After(memberBackoff):
+		}
+	}
+	if err != nil {
+		return util.KeyResult{Error: fmt.Sprintf("nessuna risposta dopo %d tentativi: %v", memberAttempts, err)}
+	}
+
+	var response util.KeyMessage
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		return util.KeyResult{Error: fmt.Sprintf("risposta non valida: %v", err)}
+	}
+	result, ok := response.Results[node.ID]
+	if !ok {
+		return util.KeyResult{Error: "risposta senza esito"}
+	}
+	return result
+}
+
+// ✅ Singolo tentativo di scambio con un membro, entro memberTimeout
+func (m *Manager) exchange(ctx context.Context, node util.NodeStatus, data []byte) ([]byte, error) {
+	ctx, cancel := clock.WithTimeout(ctx, m.clock, memberTimeout)
+	defer cancel()
+	return transport.Exchange(transport.WithPeerName(ctx, node.ID), m.transport, node.Address(), data)
+}
+
+// ✅ Applica l'operazione al keyring locale (encodedKey in base64, ignorata per list)
+func (m *Manager) Apply(op, encodedKey, sender string) util.KeyResult {
+	var err error
+	if op != OpList {
+		var key []byte
+		key, err = base64.StdEncoding.DecodeString(encodedKey)
+		if err == nil {
+			switch op {
+			case OpInstall:
+				err = m.keyring.AddKey(key)
+			case OpUse:
+				err = m.keyring.UseKey(key)
+			case OpRemove:
+				err = m.keyring.RemoveKey(key)
+			default:
+				err = fmt.Errorf("operazione sconosciuta: %s", op)
+			}
+		}
+		if err == nil {
+			util.Info(fmt.Sprintf("[KEYRING] Operazione %s eseguita (richiesta da %s)", op, sender))
+		} else {
+			util.Warn(fmt.Sprintf("[KEYRING] Operazione %s richiesta da %s fallita: %v", op, sender, err))
+		}
+	}
+
+	result := util.KeyResult{Keys: EncodeKeys(m.keyring.Keys())}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// ✅ Risposta a un messaggio ricevuto dal server TCP:
+//   - key_op: operazione propagata da un altro membro, applicata solo localmente
+//   - key_command: richiesta di un operatore, eseguita su tutto il cluster
+func (m *Manager) HandleKeyMessage(request util.KeyMessage) util.KeyMessage {
+	self := m.localMembership.Self()
+	response := util.KeyMessage{
+		Type:   "key_response",
+		Op:     request.Op,
+		Sender: self.ID,
+	}
+
+	switch request.Type {
+	case "key_op":
+		response.Results = map[string]util.KeyResult{self.ID: m.Apply(request.Op, request.Key, request.Sender)}
+	case "key_command":
+		var key []byte
+		if request.Op != OpList {
+			decoded, err := base64.StdEncoding.DecodeString(request.Key)
+			if err != nil {
+				response.Results = map[string]util.KeyResult{self.ID: {Error: fmt.Sprintf("chiave non in base64: %v", err)}}
+				return response
+			}
+			key = decoded
+		}
+		util.Info(fmt.Sprintf("[KEYRING] Operazione %s richiesta da un operatore: propagazione al cluster", request.Op))
+		response.Results = m.Execute(context.Background(), request.Op, key)
+	}
+	return response
+}
+
+// ✅ Chiavi in base64 (formato dei messaggi e della configurazione)
+func EncodeKeys(keys [][]byte) []string {
+	encoded := make([]string, len(keys))
+	for i, key := range keys {
+		encoded[i] = base64.StdEncoding.EncodeToString(key)
+	}
+	return encoded
+}
@@ -0,0 +1,252 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/transport"
+)
+
+// ✅ Caratteristiche di un collegamento (in una direzione) della rete simulata.
+// Loss, Duplicate e Reorder valgono solo per i pacchetti: gli stream (JOIN, push-pull)
+// sono affidabili come TCP e subiscono solo la latenza e le partizioni
+type Link struct {
+	Latency   time.Duration // Ritardo base di consegna
+	Jitter    time.Duration // Ritardo aggiuntivo casuale in [0, Jitter)
+	Loss      float64       // Probabilità che un pacchetto vada perso
+	Duplicate float64       // Probabilità che un pacchetto venga consegnato due volte
+	Reorder   float64       // Probabilità che un pacchetto venga trattenuto e superato dai successivi
+}
+
+// ✅ Contatori dei pacchetti transitati sulla rete simulata
+type Stats struct {
+	Sent       uint64 // Pacchetti inviati dai nodi
+	Delivered  uint64 // Consegne riuscite (i duplicati contano due volte)
+	Lost       uint64 // Pacchetti persi per Loss
+	Duplicated uint64 // Pacchetti duplicati
+	Reordered  uint64 // Pacchetti trattenuti per Reorder
+	Blocked    uint64 // Pacchetti e stream bloccati da una partizione
+}
+
+// ✅ Network: rete simulata sopra transport.MemoryNetwork, pilotata dall'orologio virtuale.
+// Ogni decisione (perdita, ritardo, duplicazione) viene estratta da un generatore con seme fisso
+type Network struct {
+	clock  *clock.Virtual
+	memory *transport.MemoryNetwork
+
+	mutex     sync.Mutex
+	random    *rand.Rand
+	link      Link           // Collegamento di default
+	links     map[route]Link // Collegamenti con caratteristiche specifiche
+	groups    map[string]int // Partizione di ciascun indirizzo (0 = gruppo di default)
+	stats     Stats
+	endpoints []*endpoint // Trasporti collegati (vedi Pending)
+}
+
+// Direzione di un collegamento (indirizzi "host:port")
+type route struct {
+	from, to string
+}
+
+// Costruttore: crea una rete simulata con il collegamento di default e il seme indicati
+func NewNetwork(clk *clock.Virtual, seed int64, link Link) *Network {
+	return &Network{
+		clock:  clk,
+		memory: transport.NewMemoryNetwork(),
+		random: rand.New(rand.NewSource(seed)),
+		link:   link,
+		links:  make(map[route]Link),
+		groups: make(map[string]int),
+	}
+}
+
+// ✅ Collega alla rete un nuovo nodo in ascolto su address
+func (nw *Network) Listen(address string) (transport.Transport, error) {
+	memory, err := nw.memory.Listen(address)
+	if err != nil {
+		return nil, err
+	}
+	e := &endpoint{Memory: memory, network: nw, address: address}
+	nw.mutex.Lock()
+	nw.endpoints = append(nw.endpoints, e)
+	nw.mutex.Unlock()
+	return e, nil
+}
+
+// ✅ Imposta le caratteristiche del collegamento from → to (una sola direzione)
+func (nw *Network) SetLink(from, to string, link Link) {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+	nw.links[route{from, to}] = link
+}
+
+// ✅ Divide la rete: gli indirizzi di gruppi diversi non si raggiungono più.
+// Gli indirizzi non elencati restano in un gruppo comune
+func (nw *Network) Partition(groups ...[]string) {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	nw.groups = make(map[string]int)
+	for i, group := range groups {
+		for _, address := range group {
+			nw.groups[address] = i + 1
+		}
+	}
+}
+
+// ✅ Rimuove tutte le partizioni
+func (nw *Network) Heal() {
+	nw.Partition()
+}
+
+// ✅ Contatori correnti
+func (nw *Network) Stats() Stats {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+	return nw.stats
+}
+
+// ✅ Pacchetti consegnati a un nodo ancora collegato e non ancora elaborati (in coda, oppure
+// letti da un server che non è ancora tornato a leggere il successivo): finché ce ne sono
+// la rete non è a riposo. Quelli ancora in transito sono timer dell'orologio virtuale
+func (nw *Network) Pending() int {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	pending := 0
+	for _, e := range nw.endpoints {
+		if e.closed.Load() {
+			continue
+		}
+		pending += e.Memory.Queued()
+		if e.handling.Load() {
+			pending++
+		}
+	}
+	return pending
+}
+
+// ✅ Ritardi di consegna di un pacchetto from → to: nessuno se perso, due se duplicato
+func (nw *Network) schedulePacket(from, to string) []time.Duration {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	nw.stats.Sent++
+	if nw.groups[from] != nw.groups[to] {
+		nw.stats.Blocked++
+		return nil
+	}
+
+	link := nw.linkLocked(from, to)
+	if nw.random.Float64() < link.Loss {
+		nw.stats.Lost++
+		return nil
+	}
+
+	delays := []time.Duration{nw.delayLocked(link)}
+	if nw.random.Float64() < link.Duplicate {
+		nw.stats.Duplicated++
+		delays = append(delays, nw.delayLocked(link))
+	}
+	if nw.random.Float64() < link.Reorder {
+		// Trattenuto oltre il ritardo massimo: i pacchetti inviati dopo arrivano prima
+		nw.stats.Reordered++
+		delays[0] += link.Latency + link.Jitter + time.Millisecond + nw.jitterLocked(link.Latency+link.Jitter)
+	}
+	return delays
+}
+
+// ✅ Latenza di apertura di uno stream from → to (errore se i nodi sono partizionati)
+func (nw *Network) streamLatency(from, to string) (time.Duration, error) {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	if nw.groups[from] != nw.groups[to] {
+		nw.stats.Blocked++
+		return 0, fmt.Errorf("connessione rifiutata: %s non raggiungibile da %s (partizione)", to, from)
+	}
+	return nw.delayLocked(nw.linkLocked(from, to)), nil
+}
+
+func (nw *Network) linkLocked(from, to string) Link {
+	if link, exists := nw.links[route{from, to}]; exists {
+		return link
+	}
+	return nw.link
+}
+
+func (nw *Network) delayLocked(link Link) time.Duration {
+	return link.Latency + nw.jitterLocked(link.Jitter)
+}
+
+func (nw *Network) jitterLocked(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(nw.random.Int63n(int64(max)))
+}
+
+// ✅ Trasporto di un nodo simulato: i pacchetti vengono consegnati dall'orologio virtuale
+// dopo il ritardo del collegamento, gli stream vengono aperti dopo la latenza
+type endpoint struct {
+	*transport.Memory
+	network  *Network
+	address  string
+	closed   atomic.Bool
+	handling atomic.Bool // L'ultimo pacchetto letto è ancora in elaborazione
+}
+
+func (e *endpoint) SendTo(address string, data []byte) error {
+	if e.closed.Load() {
+		return net.ErrClosed
+	}
+
+	payload := append([]byte(nil), data...)
+	for _, delay := range e.network.schedulePacket(e.address, address) {
+		// I pacchetti già in transito arrivano anche se nel frattempo il mittente si è arrestato
+		e.network.clock.AfterFunc(delay, func() {
+			if e.network.memory.Deliver(e.address, address, payload) {
+				e.network.mutex.Lock()
+				e.network.stats.Delivered++
+				e.network.mutex.Unlock()
+			}
+		})
+	}
+	return nil
+}
+
+// ✅ Il server UDP di un nodo legge un pacchetto alla volta: la lettura successiva indica
+// che il precedente è stato elaborato
+func (e *endpoint) ReadFrom(buffer []byte) (int, net.Addr, error) {
+	e.handling.Store(false)
+	n, addr, err := e.Memory.ReadFrom(buffer)
+	if err == nil {
+		e.handling.Store(true)
+	}
+	return n, addr, err
+}
+
+func (e *endpoint) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	latency, err := e.network.streamLatency(e.address, address)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-e.network.clock.After(latency):
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+	return e.Memory.DialContext(ctx, address)
+}
+
+func (e *endpoint) Close() error {
+	e.closed.Store(true)
+	return e.Memory.Close()
+}
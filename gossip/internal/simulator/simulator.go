@@ -0,0 +1,400 @@
+// Package simulator esegue N nodi gossip in un solo processo su una rete simulata
+// (latenza, perdite, duplicati, riordino e partizioni per collegamento) con un orologio
+// virtuale, così i test possono misurare tempi di convergenza e falsi positivi senza
+// socket reali né attese.
+//
+// Le decisioni della rete dipendono solo dal seme e il tempo avanza solo tramite Run:
+// dopo ogni scadenza il simulatore attende che i nodi abbiano finito di reagire prima di
+// proseguire. Il lavoro in corso è contato esplicitamente (pacchetti consegnati e non
+// ancora elaborati, timer scaduti e non ancora eseguiti) e le goroutine dei nodi vengono
+// attese con synctest.Wait: una simulazione va quindi creata ed eseguita dentro
+// synctest.Test, che la isola dalle goroutine degli altri test. L'ordine di esecuzione
+// delle goroutine dei nodi all'interno dello stesso istante virtuale non è controllato,
+// quindi i test devono verificare proprietà (convergenza entro un limite, tasso di falsi
+// positivi) e non sequenze esatte.
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing/synctest"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/transport"
+	"Gossip/memberlist"
+)
+
+const (
+	simulatedIP   = "10.0.0.1" // Tutti i nodi condividono l'IP e si distinguono per porta
+	firstPort     = 7000
+	stopPollLimit = 10 * time.Minute // Tempo virtuale massimo per arrestare un nodo
+)
+
+// ✅ Configurazione di una simulazione
+type Config struct {
+	Nodes int   // Numero di nodi
+	Seed  int64 // Seme delle decisioni della rete
+	Link  Link  // Caratteristiche di default di tutti i collegamenti
+
+	// Modello di configurazione dei nodi: Name, IP, Port, Transport, Clock ed Events
+	// vengono impostati dal simulatore
+	Node memberlist.Config
+}
+
+// ✅ Configurazione di default: 10 nodi su una rete locale senza perdite.
+// La rete simulata non ha processi estranei, quindi i LEAVE vengono accettati senza keyring
+func DefaultConfig() Config {
+	node := memberlist.DefaultConfig()
+	node.InsecureSelfClaims = true
+	return Config{
+		Nodes: 10,
+		Seed:  1,
+		Link:  Link{Latency: 2 * time.Millisecond, Jitter: 1 * time.Millisecond},
+		Node:  node,
+	}
+}
+
+// ✅ Evento di membership osservato da un nodo simulato
+type Event struct {
+	Time     time.Duration // Tempo virtuale trascorso dall'inizio della simulazione
+	Observer string        // Nodo che ha osservato la transizione
+	Member   string        // Nodo a cui si riferisce la transizione
+	Type     memberlist.EventType
+
+	// SUSPECT o DEAD su un nodo che in quel momento era in esecuzione
+	FalsePositive bool
+}
+
+// ✅ Simulation: cluster di nodi simulati con rete e orologio virtuali
+type Simulation struct {
+	Clock   *clock.Virtual
+	Network *Network
+
+	start time.Time
+	nodes []*simNode
+
+	mutex  sync.Mutex
+	events []Event
+}
+
+// ✅ Nodo della simulazione
+type simNode struct {
+	name      string
+	address   string
+	node      *memberlist.Node
+	transport transport.Transport
+	stopped   bool          // Crash o uscita volontaria
+	done      chan struct{} // Chiuso quando i cicli del nodo sono terminati
+}
+
+// Costruttore: crea e avvia config.Nodes nodi (non ancora collegati tra loro: vedi JoinAll).
+// Va chiamato dentro synctest.Test, come tutti i metodi della simulazione
+func New(config Config) (*Simulation, error) {
+	if config.Nodes <= 0 {
+		return nil, fmt.Errorf("numero di nodi non valido: %d", config.Nodes)
+	}
+
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	virtual := clock.NewVirtual(start)
+	s := &Simulation{
+		Clock:   virtual,
+		Network: NewNetwork(virtual, config.Seed, config.Link),
+		start:   start,
+	}
+
+	for i := 0; i < config.Nodes; i++ {
+		name := fmt.Sprintf("node%d", i)
+		port := strconv.Itoa(firstPort + i)
+		address := net.JoinHostPort(simulatedIP, port)
+
+		nodeTransport, err := s.Network.Listen(address)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		nodeConfig := config.Node
+		nodeConfig.Name = name
+		nodeConfig.IP = simulatedIP
+		nodeConfig.Port = port
+		nodeConfig.BindIP, nodeConfig.BindPort = "", ""
+		nodeConfig.Transport = nodeTransport
+		nodeConfig.Clock = virtual
+		nodeConfig.Events = &recorder{simulation: s, observer: name}
+
+		node, err := memberlist.Create(nodeConfig)
+		if err != nil {
+			nodeTransport.Close()
+			s.Close()
+			return nil, err
+		}
+		if err := node.Start(context.Background()); err != nil {
+			nodeTransport.Close()
+			s.Close()
+			return nil, err
+		}
+		s.mutex.Lock()
+		s.nodes = append(s.nodes, &simNode{name: name, address: address, node: node, transport: nodeTransport})
+		s.mutex.Unlock()
+	}
+
+	s.settle()
+	return s, nil
+}
+
+// ✅ Fa entrare tutti i nodi nel cluster usando il primo come seed (JOIN in background:
+// i tentativi procedono man mano che Run fa avanzare il tempo)
+func (s *Simulation) JoinAll() error {
+	seed := []string{s.nodes[0].address}
+	for _, sn := range s.nodes[1:] {
+		if err := sn.node.JoinInBackground(seed); err != nil {
+			return fmt.Errorf("%s: %v", sn.name, err)
+		}
+	}
+	s.settle()
+	return nil
+}
+
+// ✅ Fa avanzare il tempo virtuale di d, eseguendo in ordine tutte le scadenze
+func (s *Simulation) Run(d time.Duration) {
+	s.runUntil(s.Clock.Now().Add(d), nil)
+}
+
+// ✅ Fa avanzare il tempo finché condition è vera (controllata dopo ogni scadenza) o fino a limit.
+// Restituisce il tempo virtuale trascorso e se la condizione è stata raggiunta
+func (s *Simulation) RunUntil(condition func() bool, limit time.Duration) (time.Duration, bool) {
+	begin := s.Clock.Now()
+	reached := s.runUntil(begin.Add(limit), condition)
+	return s.Clock.Now().Sub(begin), reached
+}
+
+func (s *Simulation) runUntil(end time.Time, condition func() bool) bool {
+	for {
+		if condition != nil && condition() {
+			return true
+		}
+		next, ok := s.Clock.Next()
+		if !ok || next.After(end) {
+			break
+		}
+		s.Clock.AdvanceTo(next)
+		s.settle()
+	}
+	s.Clock.AdvanceTo(end)
+	s.settle()
+	return condition != nil && condition()
+}
+
+// ✅ Attende che i nodi abbiano finito di reagire all'ultima scadenza, senza misurare il
+// tempo reale: l'orologio non ha timer già scaduti, la rete non ha pacchetti consegnati e
+// non ancora elaborati e tutte le goroutine della simulazione sono bloccate (synctest.Wait)
+func (s *Simulation) settle() {
+	for {
+		// Timer creati con scadenza già raggiunta (es. After(0)): vanno eseguiti ora
+		s.Clock.AdvanceTo(s.Clock.Now())
+		synctest.Wait()
+
+		if !s.Clock.Due() && s.Network.Pending() == 0 {
+			return
+		}
+	}
+}
+
+// ✅ Tempo virtuale trascorso dall'inizio della simulazione
+func (s *Simulation) Elapsed() time.Duration {
+	return s.Clock.Now().Sub(s.start)
+}
+
+// ✅ Numero di nodi della simulazione (inclusi quelli arrestati)
+func (s *Simulation) Len() int {
+	return len(s.nodes)
+}
+
+// ✅ Nodo i-esimo
+func (s *Simulation) Node(i int) *memberlist.Node {
+	return s.nodes[i].node
+}
+
+// ✅ Nome del nodo i-esimo
+func (s *Simulation) Name(i int) string {
+	return s.nodes[i].name
+}
+
+// ✅ Indirizzo "ip:port" del nodo i-esimo
+func (s *Simulation) Address(i int) string {
+	return s.nodes[i].address
+}
+
+// ✅ Arresta il nodo i-esimo senza LEAVE (guasto)
+func (s *Simulation) Crash(i int) {
+	s.stop(i, false)
+}
+
+// ✅ Fa uscire il nodo i-esimo con LEAVE e lo arresta
+func (s *Simulation) Leave(i int) {
+	s.stop(i, true)
+}
+
+// ✅ Il nodo smette subito di comunicare (i pacchetti già in transito vengono consegnati);
+// i suoi cicli terminano in background man mano che il tempo avanza, perché possono
+// essere in attesa di un timer virtuale (es. l'ACK di un probe)
+func (s *Simulation) stop(i int, graceful bool) {
+	s.mutex.Lock()
+	sn := s.nodes[i]
+	if sn.stopped {
+		s.mutex.Unlock()
+		return
+	}
+	sn.stopped = true
+	sn.done = make(chan struct{})
+	s.mutex.Unlock()
+
+	if graceful {
+		sn.node.Leave(time.Second)
+	}
+	sn.transport.Close()
+	go func() {
+		sn.node.Shutdown()
+		close(sn.done)
+	}()
+	s.settle()
+}
+
+// ✅ Divide la rete in gruppi di nodi (indici) che non si raggiungono tra loro
+func (s *Simulation) Partition(groups ...[]int) {
+	addressGroups := make([][]string, len(groups))
+	for g, group := range groups {
+		for _, i := range group {
+			addressGroups[g] = append(addressGroups[g], s.nodes[i].address)
+		}
+	}
+	s.Network.Partition(addressGroups...)
+}
+
+// ✅ Rimuove tutte le partizioni
+func (s *Simulation) Heal() {
+	s.Network.Heal()
+}
+
+// ✅ Indica se tutti i nodi in esecuzione vedono esattamente i nodi in esecuzione, tutti ALIVE
+func (s *Simulation) Converged() bool {
+	running := map[string]bool{}
+	for _, sn := range s.running() {
+		running[sn.name] = true
+	}
+
+	for _, sn := range s.running() {
+		members := sn.node.Members()
+		if len(members) != len(running) {
+			return false
+		}
+		for _, member := range members {
+			if !running[member.ID] || member.Status != "alive" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *Simulation) running() []*simNode {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	running := []*simNode{}
+	for _, sn := range s.nodes {
+		if !sn.stopped {
+			running = append(running, sn)
+		}
+	}
+	return running
+}
+
+// ✅ Eventi di membership osservati finora da tutti i nodi
+func (s *Simulation) Events() []Event {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// ✅ Numero di SUSPECT/DEAD osservati su nodi in esecuzione
+func (s *Simulation) FalsePositives() int {
+	count := 0
+	for _, event := range s.Events() {
+		if event.FalsePositive {
+			count++
+		}
+	}
+	return count
+}
+
+// ✅ Arresta tutti i nodi e attende la fine dei loro cicli (facendo avanzare il tempo)
+func (s *Simulation) Close() {
+	for i := range s.nodes {
+		s.Crash(i)
+	}
+
+	limit := s.Clock.Now().Add(stopPollLimit)
+	for _, sn := range s.nodes {
+		for !isClosed(sn.done) {
+			next, ok := s.Clock.Next()
+			if !ok || next.After(limit) {
+				<-sn.done
+				break
+			}
+			s.Clock.AdvanceTo(next)
+			s.settle()
+		}
+	}
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// ✅ Registra gli eventi di un nodo nella simulazione
+type recorder struct {
+	simulation *Simulation
+	observer   string
+}
+
+func (r *recorder) record(eventType memberlist.EventType, member memberlist.Member) {
+	s := r.simulation
+	elapsed := s.Elapsed()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stopped := map[string]bool{}
+	for _, sn := range s.nodes {
+		stopped[sn.name] = sn.stopped
+	}
+	// Un nodo arrestato non osserva più nulla (i suoi cicli stanno solo terminando)
+	if stopped[r.observer] {
+		return
+	}
+
+	falsePositive := (eventType == memberlist.EventSuspect || eventType == memberlist.EventDead) && !stopped[member.ID]
+	s.events = append(s.events, Event{
+		Time:          elapsed,
+		Observer:      r.observer,
+		Member:        member.ID,
+		Type:          eventType,
+		FalsePositive: falsePositive,
+	})
+}
+
+func (r *recorder) NotifyJoin(member memberlist.Member)    { r.record(memberlist.EventJoin, member) }
+func (r *recorder) NotifyUpdate(member memberlist.Member)  { r.record(memberlist.EventUpdate, member) }
+func (r *recorder) NotifySuspect(member memberlist.Member) { r.record(memberlist.EventSuspect, member) }
+func (r *recorder) NotifyLeave(member memberlist.Member)   { r.record(memberlist.EventLeave, member) }
+func (r *recorder) NotifyDead(member memberlist.Member)    { r.record(memberlist.EventDead, member) }
@@ -0,0 +1,266 @@
+package simulator
+
+import (
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"Gossip/memberlist"
+)
+
+func TestMain(m *testing.M) {
+	// I nodi simulati scrivono molti log: nei test interessano solo le asserzioni
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func newSimulation(t *testing.T, config Config) *Simulation {
+	t.Helper()
+	s, err := New(config)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.JoinAll(); err != nil {
+		t.Fatalf("JoinAll: %v", err)
+	}
+	return s
+}
+
+func TestConvergence(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		config := DefaultConfig()
+		config.Nodes = 20
+		s := newSimulation(t, config)
+
+		elapsed, ok := s.RunUntil(s.Converged, time.Minute)
+		if !ok {
+			t.Fatalf("cluster non convergente dopo %v", elapsed)
+		}
+		t.Logf("%d nodi convergenti in %v (tempo virtuale)", config.Nodes, elapsed)
+
+		if n := s.FalsePositives(); n != 0 {
+			t.Errorf("falsi positivi su rete senza perdite: %d", n)
+		}
+	})
+}
+
+func TestCrashDetection(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		config := DefaultConfig()
+		s := newSimulation(t, config)
+		if _, ok := s.RunUntil(s.Converged, time.Minute); !ok {
+			t.Fatal("cluster non convergente")
+		}
+
+		crashed := s.Name(3)
+		s.Crash(3)
+
+		// Ogni nodo sonda un membro per ProbeInterval: entro un giro completo
+		// qualcuno ha sondato il nodo guasto e il sospetto si è diffuso
+		limit := time.Duration(config.Nodes) * config.Node.ProbeInterval * 2
+		elapsed, ok := s.RunUntil(func() bool {
+			for i := 0; i < s.Len(); i++ {
+				if i == 3 {
+					continue
+				}
+				for _, member := range s.Node(i).Members() {
+					if member.ID == crashed && member.Status == "alive" {
+						return false
+					}
+				}
+			}
+			return true
+		}, limit)
+		if !ok {
+			t.Fatalf("%s non sospettato da tutti entro %v", crashed, limit)
+		}
+		t.Logf("guasto di %s rilevato da tutti in %v", crashed, elapsed)
+
+		// SUSPECT → DEAD dopo DeadTimeout (minuti reali, istantanei in tempo virtuale)
+		limit = config.Node.DeadTimeout + 2*config.Node.FailureCheckInterval
+		deadFor := func() map[string]bool {
+			observers := map[string]bool{}
+			for _, event := range s.Events() {
+				if event.Member == crashed && event.Type == memberlist.EventDead {
+					observers[event.Observer] = true
+				}
+			}
+			return observers
+		}
+		elapsed, ok = s.RunUntil(func() bool { return len(deadFor()) == s.Len()-1 }, limit)
+		if !ok {
+			t.Fatalf("%s dichiarato DEAD solo da %d nodi entro %v", crashed, len(deadFor()), limit)
+		}
+		t.Logf("%s dichiarato DEAD da tutti dopo altri %v", crashed, elapsed)
+
+		if n := s.FalsePositives(); n != 0 {
+			t.Errorf("falsi positivi: %d", n)
+		}
+	})
+}
+
+func TestGracefulLeave(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := newSimulation(t, DefaultConfig())
+		if _, ok := s.RunUntil(s.Converged, time.Minute); !ok {
+			t.Fatal("cluster non convergente")
+		}
+
+		s.Leave(5)
+		if elapsed, ok := s.RunUntil(s.Converged, 10*time.Second); !ok {
+			t.Fatalf("uscita di %s non propagata dopo %v", s.Name(5), elapsed)
+		}
+		if n := s.FalsePositives(); n != 0 {
+			t.Errorf("falsi positivi: %d", n)
+		}
+	})
+}
+
+func TestLossyNetwork(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		config := DefaultConfig()
+		config.Link = Link{
+			Latency:   5 * time.Millisecond,
+			Jitter:    5 * time.Millisecond,
+			Loss:      0.05,
+			Duplicate: 0.05,
+			Reorder:   0.05,
+		}
+		s := newSimulation(t, config)
+		if _, ok := s.RunUntil(s.Converged, 2*time.Minute); !ok {
+			t.Fatal("cluster non convergente con perdite del 5%")
+		}
+
+		// Con i probe indiretti un falso SUSPECT richiede la perdita di ping e di tutti
+		// i ping_req: su un minuto di probe (un probe al secondo per nodo) resta raro
+		before := s.FalsePositives()
+		s.Run(time.Minute)
+		probes := config.Nodes * int(time.Minute/config.Node.ProbeInterval)
+		falsePositives := s.FalsePositives() - before
+		t.Logf("falsi positivi: %d su %d probe (%+v)", falsePositives, probes, s.Network.Stats())
+		if rate := float64(falsePositives) / float64(probes); rate > 0.02 {
+			t.Errorf("tasso di falsi positivi troppo alto: %.3f", rate)
+		}
+
+		// I sospetti vengono confutati: il cluster torna tutto ALIVE
+		if _, ok := s.RunUntil(s.Converged, time.Minute); !ok {
+			t.Error("cluster non tornato convergente dopo i sospetti")
+		}
+	})
+}
+
+func TestPartitionHeal(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := newSimulation(t, DefaultConfig())
+		if _, ok := s.RunUntil(s.Converged, time.Minute); !ok {
+			t.Fatal("cluster non convergente")
+		}
+
+		s.Partition([]int{0, 1, 2, 3, 4}, []int{5, 6, 7, 8, 9})
+		s.Run(30 * time.Second)
+		if s.Converged() {
+			t.Fatal("cluster convergente nonostante la partizione")
+		}
+
+		s.Heal()
+		if elapsed, ok := s.RunUntil(s.Converged, 2*time.Minute); !ok {
+			t.Fatalf("cluster non riunito dopo %v dalla fine della partizione", elapsed)
+		}
+	})
+}
+
+func TestJoinTimeoutVirtual(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		config := DefaultConfig()
+		config.Nodes = 1
+		config.Node.JoinTimeout = 30 * time.Second
+		s := newSimulation(t, config)
+
+		// Seed in ascolto che non accetta mai lo stream: solo il timeout può sbloccare il JOIN
+		silent, err := s.Network.Listen("10.0.0.1:7999")
+		if err != nil {
+			t.Fatalf("Listen: %v", err)
+		}
+		defer silent.Close()
+
+		result := make(chan error, 1)
+		go func() {
+			_, err := s.Node(0).Join([]string{"10.0.0.1:7999"})
+			result <- err
+		}()
+
+		var joinErr error
+		finished := func() bool {
+			select {
+			case joinErr = <-result:
+				return true
+			default:
+				return false
+			}
+		}
+		elapsed, ok := s.RunUntil(finished, time.Minute)
+		if !ok {
+			t.Fatal("JOIN non terminato allo scadere del timeout virtuale")
+		}
+		if joinErr == nil {
+			t.Fatal("JOIN riuscito verso un seed che non risponde")
+		}
+		if elapsed < config.Node.JoinTimeout {
+			t.Errorf("JOIN terminato dopo %v, prima del timeout di %v", elapsed, config.Node.JoinTimeout)
+		}
+	})
+}
+
+func TestSettleWaitsForRunningGoroutines(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		config := DefaultConfig()
+		config.Nodes = 1
+		s := newSimulation(t, config)
+
+		// Una reazione lunga alla scadenza deve finire prima che il tempo avanzi
+		var done atomic.Bool
+		expired := s.Clock.After(time.Second)
+		go func() {
+			<-expired
+			for i := 0; i < 1000; i++ {
+				runtime.Gosched()
+			}
+			done.Store(true)
+		}()
+
+		s.Run(time.Second)
+		if !done.Load() {
+			t.Fatal("il simulatore è avanzato mentre una goroutine era ancora in esecuzione")
+		}
+	})
+}
+
+func TestNetworkReproducible(t *testing.T) {
+	link := Link{Latency: 10 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 0.2, Duplicate: 0.2, Reorder: 0.2}
+	decisions := func(seed int64) [][]time.Duration {
+		network := NewNetwork(nil, seed, link)
+		result := [][]time.Duration{}
+		for i := 0; i < 200; i++ {
+			result = append(result, network.schedulePacket("10.0.0.1:7000", "10.0.0.1:7001"))
+		}
+		return result
+	}
+
+	first, second := decisions(42), decisions(42)
+	for i := range first {
+		if len(first[i]) != len(second[i]) {
+			t.Fatalf("pacchetto %d: consegne diverse con lo stesso seme", i)
+		}
+		for j := range first[i] {
+			if first[i][j] != second[i][j] {
+				t.Fatalf("pacchetto %d: ritardi diversi con lo stesso seme", i)
+			}
+		}
+	}
+}
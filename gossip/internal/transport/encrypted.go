@@ -0,0 +1,297 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"Gossip/internal/keyring"
+)
+
+// Dimensione massima del testo in chiaro di un record cifrato su stream
+const maxRecordSize = 64 * 1024
+
+// ✅ Encrypted: Transport che cifra con AES-GCM tutto il traffico di un altro Transport.
+//   - ogni pacchetto viene cifrato singolarmente, senza alcun campo in chiaro (tipo e
+//     mittente sono solo nel contenuto cifrato); quelli non decifrabili vengono
+//     rifiutati da ReadFrom con un errore keyring.ErrUndecryptable
+//   - gli stream iniziano con un valore casuale per lato e trasportano record cifrati
+//     (lunghezza + messaggio cifrato), quindi WriteFrame/ReadFrame funzionano invariati
+//     sopra la connessione. Ogni record autentica i valori iniziali, la direzione e il
+//     proprio numero d'ordine: record riordinati, ripetuti o presi da un altro stream
+//     vengono rifiutati, e Close invia un record finale che distingue la chiusura dal troncamento
+type Encrypted struct {
+	Transport
+	keyring *keyring.Keyring
+
+	readMutex  sync.Mutex // protegge readBuffer
+	readBuffer []byte     // pacchetto cifrato letto dal trasporto sottostante
+}
+
+// Costruttore: cifra il traffico di inner con le chiavi di k
+func NewEncrypted(inner Transport, k *keyring.Keyring) *Encrypted {
+	return &Encrypted{Transport: inner, keyring: k}
+}
+
+// ✅ Invia un pacchetto cifrato con la chiave primaria
+func (t *Encrypted) SendTo(address string, data []byte) error {
+	message, err := t.keyring.EncryptWithData(data, packetData)
+	if err != nil {
+		return err
+	}
+	return t.Transport.SendTo(address, message)
+}
+
+// ✅ Legge e decifra il prossimo pacchetto: tipo e mittente si trovano solo nel testo
+// decifrato, che deve essere un messaggio del protocollo
+func (t *Encrypted) ReadFrom(buffer []byte) (int, net.Addr, error) {
+	t.readMutex.Lock()
+	defer t.readMutex.Unlock()
+
+	// Buffer riusato tra le letture: serve spazio anche per nonce e tag
+	if size := len(buffer) + keyring.Overhead; len(t.readBuffer) < size {
+		t.readBuffer = make([]byte, size)
+	}
+	n, addr, err := t.Transport.ReadFrom(t.readBuffer)
+	if err != nil {
+		return 0, addr, err
+	}
+
+	plaintext, err := t.keyring.DecryptWithData(t.readBuffer[:n], packetData)
+	if err != nil {
+		return 0, addr, fmt.Errorf("pacchetto da %s: %w", addr, err)
+	}
+	if _, _, err := packetFields(plaintext); err != nil {
+		return 0, addr, fmt.Errorf("pacchetto da %s: %v: %w", addr, err, keyring.ErrUndecryptable)
+	}
+	if len(plaintext) > len(buffer) {
+		return 0, addr, fmt.Errorf("pacchetto da %s troppo grande: %d byte", addr, len(plaintext))
+	}
+	return copy(buffer, plaintext), addr, nil
+}
+
+// ✅ Tipo e mittente di un messaggio del protocollo: il mittente è un ID (LEAVE)
+// oppure la entry del nodo (tutti gli altri messaggi)
+func packetFields(data []byte) (string, string, error) {
+	var message struct {
+		Type   string          `json:"type"`
+		Sender json.RawMessage `json:"sender"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return "", "", fmt.Errorf("pacchetto non valido: %v", err)
+	}
+
+	var sender string
+	if err := json.Unmarshal(message.Sender, &sender); err != nil {
+		var node struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(message.Sender, &node); err != nil {
+			return "", "", fmt.Errorf("mittente del pacchetto non valido: %v", err)
+		}
+		sender = node.ID
+	}
+	return message.Type, sender, nil
+}
+
+// Dati autenticati di un pacchetto: distinguono i pacchetti dai record di stream
+var packetData = []byte("packet:")
+
+// ✅ Apre uno stream cifrato
+func (t *Encrypted) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	conn, err := t.Transport.DialContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedConn{Conn: conn, keyring: t.keyring, dialer: true}, nil
+}
+
+// ✅ Attende il prossimo stream in ingresso (cifrato)
+func (t *Encrypted) Accept() (net.Conn, error) {
+	conn, err := t.Transport.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedConn{Conn: conn, keyring: t.keyring}, nil
+}
+
+const (
+	helloSize   = 16 // Valore casuale inviato da ciascun lato all'apertura dello stream
+	recordFinal = 1  // Flag del record che chiude lo stream
+)
+
+// Stream chiuso senza il record finale: il resto dei dati può essere stato tagliato
+var errTruncated = fmt.Errorf("stream cifrato interrotto senza record finale: %w", io.ErrUnexpectedEOF)
+
+// ✅ Connessione che cifra ogni scrittura in uno o più record e li decifra in lettura.
+// I valori iniziali vengono scambiati alla prima lettura o scrittura: prima scrive chi
+// ha aperto lo stream, poi risponde chi lo ha accettato (net.Pipe non ha buffer)
+type encryptedConn struct {
+	net.Conn
+	keyring *keyring.Keyring
+	dialer  bool // true per chi ha aperto lo stream
+
+	handshake    sync.Once
+	handshakeErr error
+	ready        atomic.Bool // Valori iniziali scambiati: si può inviare il record finale
+	session      []byte      // Valore del dialer seguito da quello dell'acceptor
+
+	writeMutex sync.Mutex
+	sent       uint64 // Record inviati
+	received   uint64 // Record ricevuti
+	finished   bool   // Ricevuto il record finale
+	pending    []byte // Testo in chiaro decifrato e non ancora letto
+	closeOnce  sync.Once
+}
+
+// ✅ Scambia i valori iniziali (una volta sola, alla prima lettura o scrittura)
+func (c *encryptedConn) open() error {
+	c.handshake.Do(func() {
+		local := make([]byte, helloSize)
+		if _, err := rand.Read(local); err != nil {
+			c.handshakeErr = err
+			return
+		}
+
+		remote := make([]byte, helloSize)
+		if c.dialer {
+			if _, err := c.Conn.Write(local); err != nil {
+				c.handshakeErr = err
+				return
+			}
+			if _, err := io.ReadFull(c.Conn, remote); err != nil {
+				c.handshakeErr = err
+				return
+			}
+			c.session = append(local, remote...)
+		} else {
+			if _, err := io.ReadFull(c.Conn, remote); err != nil {
+				c.handshakeErr = err
+				return
+			}
+			if _, err := c.Conn.Write(local); err != nil {
+				c.handshakeErr = err
+				return
+			}
+			c.session = append(remote, local...)
+		}
+		c.ready.Store(true)
+	})
+	return c.handshakeErr
+}
+
+// ✅ Dati autenticati di un record: stream, direzione, numero d'ordine e flag
+func (c *encryptedConn) recordData(fromDialer bool, seq uint64, flags byte) []byte {
+	data := make([]byte, 0, 7+len(c.session)+10)
+	data = append(data, "stream:"...)
+	data = append(data, c.session...)
+	if fromDialer {
+		data = append(data, 0)
+	} else {
+		data = append(data, 1)
+	}
+	data = binary.BigEndian.AppendUint64(data, seq)
+	return append(data, flags)
+}
+
+func (c *encryptedConn) Write(p []byte) (int, error) {
+	if err := c.open(); err != nil {
+		return 0, err
+	}
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	written := 0
+	for written < len(p) {
+		end := min(written+maxRecordSize, len(p))
+		if err := c.writeRecordLocked(p[written:end], 0); err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+// ✅ Cifra e invia un record (il chiamante deve possedere writeMutex)
+func (c *encryptedConn) writeRecordLocked(plaintext []byte, flags byte) error {
+	record, err := c.keyring.EncryptWithData(plaintext, c.recordData(c.dialer, c.sent, flags))
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 5, 5+len(record))
+	binary.BigEndian.PutUint32(header, uint32(len(record)))
+	header[4] = flags
+	if _, err := c.Conn.Write(append(header, record...)); err != nil {
+		return err
+	}
+	c.sent++
+	return nil
+}
+
+func (c *encryptedConn) Read(p []byte) (int, error) {
+	if err := c.open(); err != nil {
+		return 0, err
+	}
+
+	for len(c.pending) == 0 {
+		if c.finished {
+			return 0, io.EOF
+		}
+
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(c.Conn, header); err != nil {
+			if err == io.EOF {
+				return 0, errTruncated
+			}
+			return 0, err
+		}
+		size, flags := binary.BigEndian.Uint32(header), header[4]
+		if size > maxRecordSize+keyring.Overhead {
+			return 0, fmt.Errorf("record cifrato troppo grande: %d byte", size)
+		}
+
+		record := make([]byte, size)
+		if _, err := io.ReadFull(c.Conn, record); err != nil {
+			return 0, err
+		}
+		plaintext, err := c.keyring.DecryptWithData(record, c.recordData(!c.dialer, c.received, flags))
+		if err != nil {
+			return 0, fmt.Errorf("record %d: %w", c.received, err)
+		}
+		c.received++
+		c.pending = plaintext
+		c.finished = flags&recordFinal != 0
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// ✅ Chiude lo stream inviando il record finale. Intanto legge e scarta i dati in arrivo,
+// così un peer che sta chiudendo a sua volta su net.Pipe non resta bloccato in scrittura
+func (c *encryptedConn) Close() error {
+	c.closeOnce.Do(func() {
+		if !c.ready.Load() || !c.writeMutex.TryLock() {
+			return // Stream mai aperto o scrittura in corso (interrotta da abort)
+		}
+		defer c.writeMutex.Unlock()
+
+		go io.Copy(io.Discard, c.Conn)
+		c.writeRecordLocked(nil, recordFinal)
+	})
+	return c.Conn.Close()
+}
+
+// ✅ Interrompe lo stream senza record finale (scadenza o cancellazione: vedi Bind)
+func (c *encryptedConn) abort() error {
+	c.closeOnce.Do(func() {})
+	return c.Conn.Close()
+}
@@ -0,0 +1,186 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"Gossip/internal/keyring"
+)
+
+func testKeyring(t *testing.T) *keyring.Keyring {
+	t.Helper()
+	k, err := keyring.New([][]byte{bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("keyring.New: %v", err)
+	}
+	return k
+}
+
+// ✅ Connessione finta: le letture vengono da r, le scritture finiscono in written
+type fakeConn struct {
+	net.Conn
+	r       io.Reader
+	written bytes.Buffer
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) { return c.written.Write(p) }
+func (c *fakeConn) Close() error                { return nil }
+func (c *fakeConn) Read(p []byte) (int, error) {
+	if c.r == nil {
+		return 0, io.EOF
+	}
+	return c.r.Read(p)
+}
+
+// ✅ Stream cifrato con i valori iniziali già scambiati
+func openedConn(k *keyring.Keyring, session []byte, dialer bool, r io.Reader) (*encryptedConn, *fakeConn) {
+	inner := &fakeConn{r: r}
+	conn := &encryptedConn{Conn: inner, keyring: k, dialer: dialer}
+	conn.handshake.Do(func() {})
+	conn.session = session
+	conn.ready.Store(true)
+	return conn, inner
+}
+
+// ✅ Divide i byte scritti da un encryptedConn nei singoli record (intestazione inclusa)
+func splitRecords(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	records := [][]byte{}
+	for len(data) > 0 {
+		size := 5 + int(binary.BigEndian.Uint32(data))
+		records = append(records, data[:size])
+		data = data[size:]
+	}
+	return records
+}
+
+func TestEncryptedStreamRecords(t *testing.T) {
+	k := testKeyring(t)
+	session := bytes.Repeat([]byte{7}, 2*helloSize)
+	other := bytes.Repeat([]byte{8}, 2*helloSize)
+
+	// Due record di dati (il payload supera maxRecordSize) e il record finale
+	payload := bytes.Repeat([]byte("gossip"), maxRecordSize/4)
+	writer, written := openedConn(k, session, true, nil)
+	if _, err := writer.Write(payload); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	writer.Close()
+	records := splitRecords(t, written.written.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("record scritti: %d, attesi 3", len(records))
+	}
+
+	tests := []struct {
+		name    string
+		session []byte
+		records [][]byte
+		wantErr error // nil: il payload viene letto per intero, poi io.EOF
+	}{
+		{"stream integro", session, records, nil},
+		{"record riordinati", session, [][]byte{records[1], records[0], records[2]}, keyring.ErrUndecryptable},
+		{"record ripetuto", session, [][]byte{records[0], records[0], records[1], records[2]}, keyring.ErrUndecryptable},
+		{"record di un altro stream", other, records, keyring.ErrUndecryptable},
+		{"stream troncato", session, records[:2], io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, _ := openedConn(k, tt.session, false, bytes.NewReader(bytes.Join(tt.records, nil)))
+			got, err := io.ReadAll(reader)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("errore %v, atteso %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, payload) {
+				t.Fatalf("letti %d byte (errore %v), attesi %d", len(got), err, len(payload))
+			}
+		})
+	}
+}
+
+func TestEncryptedStreamExchange(t *testing.T) {
+	k := testKeyring(t)
+	network := NewMemoryNetwork()
+	server, _ := network.Listen("127.0.0.1:9000")
+	client, _ := network.Listen("127.0.0.1:9001")
+	defer server.Close()
+	defer client.Close()
+	encryptedServer, encryptedClient := NewEncrypted(server, k), NewEncrypted(client, k)
+
+	go func() {
+		conn, err := encryptedServer.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if request, err := ReadFrame(conn); err == nil {
+			WriteFrame(conn, append([]byte("eco "), request...))
+		}
+	}()
+
+	response, err := Exchange(t.Context(), encryptedClient, "127.0.0.1:9000", []byte("ping"))
+	if err != nil || string(response) != "eco ping" {
+		t.Fatalf("Exchange: %q, %v", response, err)
+	}
+}
+
+func TestEncryptedPacket(t *testing.T) {
+	k := testKeyring(t)
+	network := NewMemoryNetwork()
+	receiver, _ := network.Listen("127.0.0.1:9000")
+	sender, _ := network.Listen("127.0.0.1:9001")
+	defer receiver.Close()
+	defer sender.Close()
+	encryptedReceiver := NewEncrypted(receiver, k)
+
+	message := []byte(`{"type":"leave","sender":"node1"}`)
+	sealed, _ := k.EncryptWithData(message, packetData)
+	notProtocol, _ := k.EncryptWithData([]byte("ciao"), packetData)
+
+	// Nulla del messaggio è leggibile sulla rete: né il tipo né il nome del mittente
+	if bytes.Contains(sealed, []byte("leave")) || bytes.Contains(sealed, []byte("node1")) {
+		t.Fatalf("il pacchetto cifrato contiene tipo o mittente in chiaro: %q", sealed)
+	}
+
+	tests := []struct {
+		name   string
+		packet []byte
+		wantOK bool
+	}{
+		{"pacchetto cifrato", sealed, true},
+		{"cifrato senza dati autenticati", mustEncrypt(t, k, message), false},
+		{"messaggio in chiaro", message, false},
+		{"contenuto non del protocollo", notProtocol, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender.SendTo("127.0.0.1:9000", tt.packet)
+			buffer := make([]byte, 1024)
+			n, _, err := encryptedReceiver.ReadFrom(buffer)
+			if tt.wantOK {
+				if err != nil || !bytes.Equal(buffer[:n], message) {
+					t.Fatalf("ReadFrom: %q, %v", buffer[:n], err)
+				}
+				return
+			}
+			if !errors.Is(err, keyring.ErrUndecryptable) {
+				t.Fatalf("errore %v, atteso ErrUndecryptable", err)
+			}
+		})
+	}
+}
+
+func mustEncrypt(t *testing.T, k *keyring.Keyring, plaintext []byte) []byte {
+	t.Helper()
+	message, err := k.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	return message
+}
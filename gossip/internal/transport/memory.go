@@ -0,0 +1,199 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Pacchetti in coda per ciascun nodo in memoria: oltre questo limite vengono scartati (come UDP)
+const memoryQueueSize = 1024
+
+// ✅ MemoryNetwork: rete simulata in memoria a cui si collegano i trasporti Memory.
+// Permette di eseguire centinaia di nodi in un solo processo senza socket reali
+type MemoryNetwork struct {
+	mutex     sync.RWMutex
+	endpoints map[string]*Memory
+}
+
+// Costruttore: crea una rete in memoria vuota
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{endpoints: make(map[string]*Memory)}
+}
+
+// ✅ Collega un nuovo trasporto all'indirizzo "host:port" (deve essere libero)
+func (network *MemoryNetwork) Listen(address string) (*Memory, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("indirizzo non valido %s: %v", address, err)
+	}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	if _, exists := network.endpoints[address]; exists {
+		return nil, fmt.Errorf("indirizzo già in uso: %s", address)
+	}
+	endpoint := &Memory{
+		network: network,
+		addr:    memoryAddr(address),
+		packets: make(chan memoryPacket, memoryQueueSize),
+		streams: make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+	network.endpoints[address] = endpoint
+	return endpoint, nil
+}
+
+// ✅ Consegna un pacchetto a chi è in ascolto su to, come inviato da from.
+// Restituisce false se il pacchetto si perde (nessuno in ascolto o coda piena).
+// Le reti simulate lo usano per i pacchetti in transito, anche se il mittente è già chiuso
+func (network *MemoryNetwork) Deliver(from, to string, data []byte) bool {
+	target := network.endpoint(to)
+	if target == nil {
+		return false
+	}
+	packet := memoryPacket{from: memoryAddr(from), data: append([]byte(nil), data...)}
+	select {
+	case target.packets <- packet:
+		return true
+	case <-target.closed:
+		return false
+	default:
+		return false
+	}
+}
+
+// ✅ Trasporto collegato all'indirizzo dato (nil se nessuno è in ascolto)
+func (network *MemoryNetwork) endpoint(address string) *Memory {
+	network.mutex.RLock()
+	defer network.mutex.RUnlock()
+	return network.endpoints[address]
+}
+
+// ✅ Memory: implementazione di Transport su MemoryNetwork.
+// I pacchetti passano su un canale (e vengono scartati se il destinatario non esiste
+// o ha la coda piena), gli stream sono coppie di net.Pipe
+type Memory struct {
+	network   *MemoryNetwork
+	addr      memoryAddr
+	packets   chan memoryPacket
+	streams   chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Pacchetto in transito con l'indirizzo del mittente
+type memoryPacket struct {
+	from memoryAddr
+	data []byte
+}
+
+// ✅ Consegna un pacchetto al trasporto in ascolto su address
+func (t *Memory) SendTo(address string, data []byte) error {
+	if t.isClosed() {
+		return net.ErrClosed
+	}
+
+	// Un pacchetto perso (nessuno in ascolto o coda piena) non è un errore, come per UDP
+	t.network.Deliver(string(t.addr), address, data)
+	return nil
+}
+
+// ✅ Legge il prossimo pacchetto ricevuto (ritorna net.ErrClosed dopo Close)
+func (t *Memory) ReadFrom(buffer []byte) (int, net.Addr, error) {
+	select {
+	case packet := <-t.packets:
+		n := copy(buffer, packet.data)
+		return n, packet.from, nil
+	case <-t.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+// ✅ Pacchetti consegnati e non ancora letti con ReadFrom (0 dopo Close)
+func (t *Memory) Queued() int {
+	if t.isClosed() {
+		return 0
+	}
+	return len(t.packets)
+}
+
+// ✅ Apre uno stream verso il trasporto in ascolto su address
+func (t *Memory) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	if t.isClosed() {
+		return nil, net.ErrClosed
+	}
+
+	target := t.network.endpoint(address)
+	if target == nil {
+		return nil, fmt.Errorf("connessione rifiutata: nessun nodo in ascolto su %s", address)
+	}
+
+	local, remote := net.Pipe()
+	select {
+	case target.streams <- memoryConn{Conn: remote, local: target.addr, remote: t.addr}:
+		return memoryConn{Conn: local, local: t.addr, remote: target.addr}, nil
+	case <-target.closed:
+		local.Close()
+		remote.Close()
+		return nil, fmt.Errorf("connessione rifiutata: nessun nodo in ascolto su %s", address)
+	case <-ctx.Done():
+		local.Close()
+		remote.Close()
+		return nil, context.Cause(ctx)
+	}
+}
+
+// ✅ Attende il prossimo stream in ingresso (ritorna net.ErrClosed dopo Close)
+func (t *Memory) Accept() (net.Conn, error) {
+	select {
+	case conn := <-t.streams:
+		return conn, nil
+	case <-t.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// ✅ Indirizzo locale di ascolto
+func (t *Memory) LocalAddr() net.Addr {
+	return t.addr
+}
+
+// ✅ Scollega il trasporto dalla rete: l'indirizzo torna libero
+func (t *Memory) Close() error {
+	t.closeOnce.Do(func() {
+		t.network.mutex.Lock()
+		if t.network.endpoints[string(t.addr)] == t {
+			delete(t.network.endpoints, string(t.addr))
+		}
+		t.network.mutex.Unlock()
+		close(t.closed)
+	})
+	return nil
+}
+
+func (t *Memory) isClosed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ✅ Indirizzo "host:port" di un trasporto in memoria
+type memoryAddr string
+
+func (a memoryAddr) Network() string { return "memory" }
+func (a memoryAddr) String() string  { return string(a) }
+
+// ✅ Stream in memoria con gli indirizzi dei due nodi (net.Pipe non li conosce)
+type memoryConn struct {
+	net.Conn
+	local  memoryAddr
+	remote memoryAddr
+}
+
+func (c memoryConn) LocalAddr() net.Addr  { return c.local }
+func (c memoryConn) RemoteAddr() net.Addr { return c.remote }
@@ -0,0 +1,69 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ✅ Net: implementazione di Transport su rete reale, un'unica porta sia UDP sia TCP.
+//   - UDP per i messaggi piccoli (probe, rumour, LEAVE): tutti partono dalla porta di
+//     ascolto, quindi le risposte tornano al listener e i firewall aprono una sola porta
+//   - TCP (frame con lunghezza in testa) per lo stato completo: push-pull e JOIN,
+//     che non entrano in un singolo datagramma quando il cluster cresce
+type Net struct {
+	packetConn net.PacketConn
+	listener   net.Listener
+}
+
+// ✅ Apre UDP e TCP sull'indirizzo locale address ("ip:port", ip vuoto = tutte le interfacce)
+func Listen(address string) (*Net, error) {
+	packetConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		packetConn.Close()
+		return nil, err
+	}
+	return &Net{packetConn: packetConn, listener: listener}, nil
+}
+
+// ✅ Invia un datagramma UDP all'indirizzo "host:port"
+func (t *Net) SendTo(address string, data []byte) error {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return fmt.Errorf("indirizzo non valido %s: %v", address, err)
+	}
+	_, err = t.packetConn.WriteTo(data, addr)
+	return err
+}
+
+// ✅ Legge il prossimo datagramma UDP (ritorna net.ErrClosed dopo Close)
+func (t *Net) ReadFrom(buffer []byte) (int, net.Addr, error) {
+	return t.packetConn.ReadFrom(buffer)
+}
+
+// ✅ Apre una connessione TCP verso "host:port" (al massimo fino alla scadenza di ctx)
+func (t *Net) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// ✅ Attende la prossima connessione TCP in ingresso (ritorna net.ErrClosed dopo Close)
+func (t *Net) Accept() (net.Conn, error) {
+	return t.listener.Accept()
+}
+
+// ✅ Indirizzo locale di ascolto
+func (t *Net) LocalAddr() net.Addr {
+	return t.packetConn.LocalAddr()
+}
+
+// ✅ Chiude UDP e TCP: i server del nodo terminano
+func (t *Net) Close() error {
+	return errors.Join(t.packetConn.Close(), t.listener.Close())
+}
@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+)
+
+// ✅ File PEM per il TLS mutuo degli stream
+type TLSFiles struct {
+	CertFile string // Certificato del nodo (usato sia come server sia come client)
+	KeyFile  string // Chiave privata del certificato
+	CAFile   string // CA con cui verificare i certificati degli altri nodi
+}
+
+// ✅ Certificato e CA caricati dai file (sostituiti in blocco da Reload)
+type tlsMaterial struct {
+	certificate tls.Certificate
+	roots       *x509.CertPool
+}
+
+// ✅ TLS: Transport che protegge gli stream (push-pull, JOIN, comandi sulle chiavi) con TLS mutuo.
+//   - entrambi i lati presentano un certificato emesso dalla CA configurata
+//   - chi apre la connessione verifica che il certificato del server sia intestato al nome
+//     del nodo atteso: quello indicato con WithPeerName (obbligatorio verso i membri noti)
+//     oppure quello del nodo che usa l'indirizzo (nameFor). Solo per un seed prima del JOIN
+//     il nome non è noto e viene verificata solo la catena: il seed entra nella Membership
+//     List solo dopo aver risposto con il proprio nome, e i contatti successivi lo verificano
+//   - chi accetta la connessione verifica la catena; il nome del client viene confrontato
+//     con quello dichiarato nel primo frame dello stream (vedi VerifyPeerName)
+//   - i pacchetti UDP passano invariati
+type TLS struct {
+	Transport
+	files    TLSFiles
+	nameFor  func(address string) string
+	material atomic.Pointer[tlsMaterial]
+}
+
+// Costruttore: carica certificato e CA da files. nameFor restituisce il nome atteso nel
+// certificato del nodo in ascolto su un indirizzo ("" se non è noto)
+func NewTLS(inner Transport, files TLSFiles, nameFor func(address string) string) (*TLS, error) {
+	t := &TLS{Transport: inner, files: files, nameFor: nameFor}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ✅ Rilegge certificato, chiave e CA dai file: le nuove connessioni usano i nuovi valori,
+// quelle già aperte non cambiano. In caso di errore restano in uso quelli precedenti
+func (t *TLS) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(t.files.CertFile, t.files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("certificato TLS: %v", err)
+	}
+	caPEM, err := os.ReadFile(t.files.CAFile)
+	if err != nil {
+		return fmt.Errorf("CA TLS: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("CA TLS: nessun certificato PEM in %s", t.files.CAFile)
+	}
+
+	t.material.Store(&tlsMaterial{certificate: certificate, roots: roots})
+	return nil
+}
+
+// ✅ Apre uno stream e completa l'handshake TLS (entro la scadenza di ctx)
+func (t *TLS) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	conn, err := t.Transport.DialContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	name := PeerName(ctx)
+	if name == "" {
+		name = t.nameFor(address)
+	}
+	tlsConn := tls.Client(conn, t.clientConfig(name))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake TLS con %s: %w", address, err)
+	}
+	return tlsConn, nil
+}
+
+// ✅ Attende il prossimo stream in ingresso: l'handshake avviene alla prima lettura,
+// entro la scadenza impostata da chi gestisce la connessione
+func (t *TLS) Accept() (net.Conn, error) {
+	conn, err := t.Transport.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Server(conn, t.serverConfig()), nil
+}
+
+// ✅ Configurazione client: la verifica è manuale (VerifyConnection) per usare la CA
+// corrente e il nome del nodo invece dell'hostname dell'indirizzo
+func (t *TLS) clientConfig(serverName string) *tls.Config {
+	material := t.material.Load()
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		Certificates:       []tls.Certificate{material.certificate},
+		InsecureSkipVerify: true, // Sostituito da VerifyConnection
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifyPeer(state, material.roots, serverName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+}
+
+// ✅ Configurazione server: certificato client obbligatorio ed emesso dalla CA. Il nome del
+// client non è ancora noto durante l'handshake: lo verifica VerifyPeerName dopo il primo frame
+func (t *TLS) serverConfig() *tls.Config {
+	material := t.material.Load()
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{material.certificate},
+		ClientAuth:   tls.RequireAnyClientCert, // La catena viene verificata in VerifyConnection
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifyPeer(state, material.roots, "", x509.ExtKeyUsageClientAuth)
+		},
+	}
+}
+
+// Chiave del contesto per il nome del nodo atteso all'altro capo di uno stream
+type peerNameKey struct{}
+
+// ✅ Indica il nome del nodo con cui si vuole aprire lo stream: con il TLS mutuo il suo
+// certificato deve essere intestato a name. Va usato per tutti i membri noti
+func WithPeerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, peerNameKey{}, name)
+}
+
+// ✅ Nome del nodo atteso indicato con WithPeerName ("" se assente)
+func PeerName(ctx context.Context) string {
+	name, _ := ctx.Value(peerNameKey{}).(string)
+	return name
+}
+
+// ✅ Verifica che il certificato TLS del client di uno stream accettato sia intestato a name,
+// il nodo dichiarato nel primo frame. Senza TLS non c'è nulla da verificare (nil)
+func VerifyPeerName(conn net.Conn, name string) error {
+	for {
+		switch c := conn.(type) {
+		case *encryptedConn:
+			conn = c.Conn
+		case *tls.Conn:
+			certificates := c.ConnectionState().PeerCertificates
+			if len(certificates) == 0 {
+				return errors.New("nessun certificato presentato")
+			}
+			return certificates[0].VerifyHostname(name)
+		default:
+			return nil
+		}
+	}
+}
+
+// ✅ Verifica la catena del certificato presentato dal peer e, se name non è vuoto, il nome
+func verifyPeer(state tls.ConnectionState, roots *x509.CertPool, name string, usage x509.ExtKeyUsage) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("nessun certificato presentato")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       name,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
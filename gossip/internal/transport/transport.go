@@ -0,0 +1,119 @@
+// Package transport contiene il livello di rete del nodo: l'interfaccia Transport
+// usata da gossip, join, leave e failure detection, con un'implementazione su rete
+// reale (Net, UDP + TCP) e una in memoria (Memory) per i test con molti nodi.
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+)
+
+// Dimensione massima di un frame su stream (membership di migliaia di nodi)
+const MaxFrameSize = 32 * 1024 * 1024
+
+// Blocco di lettura di un frame: la memoria allocata segue i byte ricevuti, non la lunghezza dichiarata
+const readChunkSize = 64 * 1024
+
+// ✅ Transport: trasporto di un nodo, con due canali verso gli altri nodi
+//   - pacchetti (datagrammi non affidabili) per i messaggi piccoli: probe, rumour, LEAVE
+//   - stream (connessioni affidabili) per lo stato completo: push-pull e JOIN
+//
+// Gli indirizzi sono stringhe "host:port"; dopo Close, ReadFrom e Accept ritornano net.ErrClosed
+type Transport interface {
+	// Invia un pacchetto all'indirizzo dato (un pacchetto perso non è un errore)
+	SendTo(address string, data []byte) error
+	// Legge il prossimo pacchetto ricevuto
+	ReadFrom(buffer []byte) (int, net.Addr, error)
+	// Apre uno stream verso l'indirizzo dato
+	DialContext(ctx context.Context, address string) (net.Conn, error)
+	// Attende il prossimo stream in ingresso
+	Accept() (net.Conn, error)
+	// Indirizzo locale di ascolto
+	LocalAddr() net.Addr
+	// Chiude il trasporto: i server del nodo terminano
+	Close() error
+}
+
+// ✅ Scambio richiesta/risposta su uno stream dedicato: invia un frame
+// e attende il frame di risposta (al massimo fino alla scadenza di ctx)
+func Exchange(ctx context.Context, t Transport, address string, request []byte) ([]byte, error) {
+	conn, err := t.DialContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	defer Bind(ctx, conn)()
+
+	if err := WriteFrame(conn, request); err != nil {
+		return nil, err
+	}
+	response, err := ReadFrame(conn)
+	if err != nil && ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	return response, err
+}
+
+// ✅ Lega la connessione a ctx: la cancellazione (o la scadenza) di ctx la interrompe e
+// sblocca lettura e scrittura. Restituisce la funzione che scioglie il legame
+func Bind(ctx context.Context, conn net.Conn) func() bool {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return context.AfterFunc(ctx, func() {
+		// Uno stream cifrato interrotto non invia il record finale (il peer potrebbe non leggerlo mai)
+		if aborter, ok := conn.(interface{ abort() error }); ok {
+			aborter.abort()
+			return
+		}
+		conn.Close()
+	})
+}
+
+// ✅ Scrive un frame: lunghezza (4 byte big-endian) seguita dai dati
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("frame troppo grande: %d byte", len(data))
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// ✅ Legge un frame scritto da WriteFrame
+func ReadFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size > MaxFrameSize {
+		return nil, fmt.Errorf("frame troppo grande: %d byte", size)
+	}
+
+	// La lunghezza arriva dal peer: il buffer cresce solo man mano che i dati arrivano davvero
+	data := make([]byte, 0, min(int(size), readChunkSize))
+	for len(data) < int(size) {
+		n := min(int(size)-len(data), readChunkSize)
+		data = slices.Grow(data, n)
+		if _, err := io.ReadFull(r, data[len(data):len(data)+n]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		data = data[:len(data)+n]
+	}
+	return data, nil
+}
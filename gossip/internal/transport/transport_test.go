@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func TestReadFrame(t *testing.T) {
+	frame := func(size uint32, data []byte) []byte {
+		header := binary.BigEndian.AppendUint32(nil, size)
+		return append(header, data...)
+	}
+	large := bytes.Repeat([]byte("x"), 3*readChunkSize+1)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    []byte
+		wantErr error
+	}{
+		{"frame vuoto", frame(0, nil), []byte{}, nil},
+		{"frame piccolo", frame(4, []byte("ping")), []byte("ping"), nil},
+		{"frame su più blocchi", frame(uint32(len(large)), large), large, nil},
+		{"frame troncato", frame(MaxFrameSize, []byte("ping")), nil, io.ErrUnexpectedEOF},
+		{"intestazione troncata", []byte{0, 0}, nil, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFrame(bytes.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) || !bytes.Equal(got, tt.want) {
+				t.Fatalf("ReadFrame: %d byte, errore %v; attesi %d byte, errore %v", len(got), err, len(tt.want), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadFrameAllocatesReceivedBytes(t *testing.T) {
+	// Un peer che dichiara il frame massimo e poi invia pochi byte non fa allocare MaxFrameSize
+	input := binary.BigEndian.AppendUint32(nil, MaxFrameSize)
+	input = append(input, []byte("ping")...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ReadFrame(bytes.NewReader(input))
+	runtime.ReadMemStats(&after)
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 4*readChunkSize {
+		t.Fatalf("allocati %d byte per un frame di 4 byte", allocated)
+	}
+}
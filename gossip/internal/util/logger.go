@@ -1,18 +1,53 @@
 package util
 
 import (
+	"fmt"
 	"log"
+	"strings"
 	"time"
 )
 
+// ✅ Livelli di log in ordine crescente di gravità
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Livello minimo dei messaggi stampati (impostabile da configurazione)
+var logLevel = LevelInfo
+
+// ✅ Imposta il livello di log a partire dal nome (debug, info, warn, error)
+func SetLogLevel(level string) error {
+	switch strings.ToLower(level) {
+	case "debug":
+		logLevel = LevelDebug
+	case "info":
+		logLevel = LevelInfo
+	case "warn":
+		logLevel = LevelWarn
+	case "error":
+		logLevel = LevelError
+	default:
+		return fmt.Errorf("livello di log sconosciuto: %s", level)
+	}
+	DebugEnabled = logLevel == LevelDebug
+	return nil
+}
+
 // Funzione per loggare messaggi informativi
 func Info(message string) {
-	log.Printf("[INFO] [%s] %s", time.Now().Format(time.RFC3339), message)
+	if logLevel <= LevelInfo {
+		log.Printf("[INFO] [%s] %s", time.Now().Format(time.RFC3339), message)
+	}
 }
 
 // Funzione per loggare messaggi di avviso (warning)
 func Warn(message string) {
-	log.Printf("[WARN] [%s] %s", time.Now().Format(time.RFC3339), message)
+	if logLevel <= LevelWarn {
+		log.Printf("[WARN] [%s] %s", time.Now().Format(time.RFC3339), message)
+	}
 }
 
 // Funzione per loggare messaggi di errore
@@ -21,7 +56,7 @@ func Error(message string) {
 }
 
 // Funzione per loggare messaggi di debug (opzionale, attivabile/disattivabile)
-var DebugEnabled = true
+var DebugEnabled = false
 
 func Debug(message string) {
 	if DebugEnabled {
@@ -1,12 +1,27 @@
 package util
 
+import "net"
+
 // ✅ Struttura che rappresenta lo stato di un nodo nella rete
 type NodeStatus struct {
-	ID       string `json:"id"`        // Identificativo univoco del nodo (es. "node1")
-	IP       string `json:"ip"`        // Indirizzo IP del nodo
-	Port     string `json:"port"`      // Porta su cui il nodo ascolta
-	Status   string `json:"status"`    // Stato del nodo: alive, suspect, dead
-	LastSeen string `json:"last_seen"` // Timestamp dell'ultima volta visto (RFC3339)
+	ID          string `json:"id"`          // Nome univoco e stabile del nodo (es. "node1"), indipendente dall'indirizzo
+	IP          string `json:"ip"`          // Indirizzo IP/hostname annunciato agli altri nodi
+	Port        string `json:"port"`        // Porta annunciata agli altri nodi
+	Status      string `json:"status"`      // Stato del nodo: alive, suspect, dead, left
+	Incarnation uint64 `json:"incarnation"` // Numero di incarnazione (incrementato solo dal nodo stesso)
+	Heartbeat   uint64 `json:"heartbeat"`   // Contatore heartbeat (incrementato solo dal nodo stesso)
+	LastSeen    string `json:"-"`           // Timestamp LOCALE dell'ultimo heartbeat ricevuto (RFC3339Nano, non viene trasmesso)
+	StatusSince string `json:"-"`           // Timestamp LOCALE dell'ingresso nello stato corrente (es. inizio del sospetto, non viene trasmesso)
+
+	// Identità (solo se attiva, vedi internal/identity): firma del nodo sulla propria entry
+	PublicKey   []byte `json:"public_key,omitempty"`  // Chiave pubblica Ed25519 del nodo
+	Certificate []byte `json:"certificate,omitempty"` // Firma della CA su ID e chiave pubblica
+	Signature   []byte `json:"signature,omitempty"`   // Firma del nodo sui campi dell'entry
+}
+
+// ✅ Indirizzo "ip:port" annunciato dal nodo (può cambiare, l'ID no)
+func (n NodeStatus) Address() string {
+	return net.JoinHostPort(n.IP, n.Port)
 }
 
 // ✅ Messaggio utilizzato per Gossip Update, JOIN_ACK, ecc.
@@ -14,6 +29,8 @@ type GossipMessage struct {
 	Type       string       `json:"type"`
 	Sender     NodeStatus   `json:"sender"`
 	Membership []NodeStatus `json:"membership,omitempty"`
+	Rumours    []NodeStatus `json:"rumours,omitempty"`  // Cambiamenti di stato in piggyback
+	Observed   string       `json:"observed,omitempty"` // Indirizzo da cui è arrivata la richiesta (solo join_ack)
 }
 
 // ✅ Messaggio di JOIN (richiesta di entrare nella rete)
@@ -24,6 +41,32 @@ type JoinMessage struct {
 
 // ✅ Messaggio di LEAVE (richiesta di uscire dalla rete)
 type LeaveMessage struct {
-	Type   string `json:"type"`   // Tipo del messaggio: "leave"
-	Sender string `json:"sender"` // ID del nodo che vuole lasciare la rete (stringa, perché basta ID)
+	Type   string     `json:"type"`   // Tipo del messaggio: "leave"
+	Sender string     `json:"sender"` // ID del nodo che vuole lasciare la rete
+	Node   NodeStatus `json:"node"`   // Entry LEFT del nodo (firmata se le identità sono attive)
+}
+
+// ✅ Messaggio di probe SWIM (ping, ping_req, ack)
+type ProbeMessage struct {
+	Type   string     `json:"type"`   // Tipo del messaggio: "ping", "ping_req" o "ack"
+	SeqNo  uint64     `json:"seq_no"` // Numero di sequenza per associare ping e ack
+	Sender NodeStatus `json:"sender"` // Nodo che invia il messaggio (a cui va inviata la risposta)
+	Target NodeStatus `json:"target"` // Nodo da sondare per conto del mittente (solo per ping_req)
+
+	Rumours []NodeStatus `json:"rumours,omitempty"` // Cambiamenti di stato in piggyback
+}
+
+// ✅ Messaggio di gestione delle chiavi di cifratura (rotazione online del keyring)
+type KeyMessage struct {
+	Type    string               `json:"type"`              // "key_op" (verso un membro), "key_command" (da un operatore) o "key_response"
+	Op      string               `json:"op,omitempty"`      // install, use, remove, list
+	Key     string               `json:"key,omitempty"`     // Chiave in base64 (non serve per list)
+	Sender  string               `json:"sender,omitempty"`  // Nodo che ha avviato l'operazione
+	Results map[string]KeyResult `json:"results,omitempty"` // Esito per nodo (solo key_response)
+}
+
+// ✅ Esito di un'operazione sulle chiavi su un singolo nodo
+type KeyResult struct {
+	Error string   `json:"error,omitempty"` // Vuoto se l'operazione è riuscita
+	Keys  []string `json:"keys,omitempty"`  // Chiavi installate dopo l'operazione (base64), la primaria per prima
 }
@@ -0,0 +1,300 @@
+package memberlist
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gossip/internal/clock"
+	"Gossip/internal/failure"
+	"Gossip/internal/gossip"
+	"Gossip/internal/join"
+	"Gossip/internal/keyring"
+	"Gossip/internal/transport"
+)
+
+// Tipi di failure detector selezionabili in Config.FailureDetector
+const (
+	DetectorTimeout = failure.DetectorTimeout // Soglie fisse su LastSeen (default)
+	DetectorPhi     = failure.DetectorPhi     // Phi-accrual sugli intervalli di arrivo degli heartbeat
+)
+
+// ✅ Orologio usato dai cicli del nodo (vedi Config.Clock)
+type Clock = clock.Clock
+
+// ✅ Ticker restituito da Clock.NewTicker
+type Ticker = clock.Ticker
+
+// ✅ Crea il Ticker da restituire in un'implementazione di Clock: riceve i tick da c e
+// si ferma chiamando stop (es. NewTicker(t.C, t.Stop) con t := time.NewTicker(d))
+func NewTicker(c <-chan time.Time, stop func()) *Ticker {
+	return clock.NewTicker(c, stop)
+}
+
+// ✅ Configurazione di un Node embeddabile
+type Config struct {
+	Name string // Nome univoco e stabile del nodo nel cluster
+
+	// Indirizzo annunciato agli altri nodi (quello con cui ci raggiungono, es. dietro NAT
+	// o port mapping di Docker): un IP o un hostname, mai un indirizzo non specificato (0.0.0.0)
+	IP   string
+	Port string
+
+	// Con IP vuoto l'indirizzo annunciato viene scoperto durante il JOIN dall'indirizzo da cui
+	// i seed vedono arrivare le nostre richieste: serve almeno un seed (vedi Join). Senza
+	// DiscoverIP un IP vuoto è un errore di configurazione
+	DiscoverIP bool
+
+	// Indirizzo locale di ascolto: BindIP vuoto = tutte le interfacce, BindPort vuota = Port
+	BindIP   string
+	BindPort string
+
+	// Gossip
+	GossipInterval   time.Duration // Frequenza di diffusione dei rumour
+	GossipFanout     int           // Peer contattati ad ogni round di gossip
+	PushPullInterval time.Duration // Frequenza della sincronizzazione completa (anti-entropy)
+
+	// Failure detection
+	FailureDetector      string        // DetectorTimeout oppure DetectorPhi
+	PhiThreshold         float64       // Soglia phi per SUSPECT (DEAD oltre il doppio)
+	FailureCheckInterval time.Duration // Frequenza dei controlli del detector a soglie fisse
+	DeadTimeout          time.Duration // SUSPECT da più di così (senza confutazione) → DEAD
+	TombstoneRetention   time.Duration // Durata dei tombstone DEAD/LEFT
+	ProbeInterval        time.Duration // Ogni quanto viene sondato un membro
+	ProbeTimeout         time.Duration // Attesa dell'ACK diretto prima dei ping_req
+	IndirectChecks       int           // Membri a cui chiedere il probe indiretto
+
+	// Join
+	JoinTimeout        time.Duration // Tempo massimo per ottenere una JOIN_ACK da almeno un seed
+	JoinInitialBackoff time.Duration // Attesa iniziale tra due tentativi verso lo stesso seed
+	JoinMaxBackoff     time.Duration // Limite del backoff esponenziale tra i tentativi
+
+	// Eventi
+	Events EventDelegate // Notifiche delle transizioni di stato dei membri (opzionale)
+
+	// Cifratura AES-GCM di tutto il traffico (opzionale): chiavi da 16, 24 o 32 byte.
+	// La prima cifra i messaggi, tutte vengono accettate in decifratura; nil = traffico in chiaro.
+	// L'autenticazione dei messaggi dipende da cifratura e identità, non dall'indirizzo sorgente
+	// (che dietro NAT non coincide con quello annunciato): con le sole EncryptionKeys ogni membro
+	// che conosce la chiave è fidato, con le identità ogni entry deve essere firmata dal nodo che
+	// descrive. Senza né l'una né l'altre i messaggi NON sono autenticati: LEAVE, confutazioni
+	// ALIVE ed entry LEFT vengono rifiutati (un nodo uscito viene rilevato dal failure detector),
+	// mentre rumour e push-pull restano esposti a chiunque raggiunga la porta
+	EncryptionKeys [][]byte
+
+	// Accetta LEAVE, confutazioni ALIVE ed entry LEFT anche senza EncryptionKeys né identità.
+	// Chiunque raggiunga la porta può allora far uscire qualsiasi membro: solo per reti fidate
+	InsecureSelfClaims bool
+
+	// Identità Ed25519 del nodo (opzionale): il nodo firma la propria entry e accetta solo
+	// entry firmate da membri con un certificato emesso da una delle TrustedKeys.
+	// IdentityCertificate è la firma della CA su Name e chiave pubblica (vedi IssueCertificate).
+	// Con le identità attive l'indirizzo annunciato (IP) deve essere configurato
+	IdentityKey         ed25519.PrivateKey
+	IdentityCertificate []byte
+	TrustedKeys         []ed25519.PublicKey // Chiavi pubbliche delle CA fidate
+
+	// TLS mutuo sugli stream TCP (push-pull, JOIN, comandi sulle chiavi), opzionale: file PEM
+	// di certificato, chiave e CA. Il certificato di ogni nodo deve contenere Name tra i nomi DNS
+	// ed essere valido sia come server sia come client. I file vengono riletti da ReloadTLS
+	TLSCertFile string
+	TLSKeyFile  string
+	TLSCAFile   string
+
+	// Trasporto (opzionale): nil = UDP e TCP reali su BindIP:BindPort. Un Transport
+	// fornito dal chiamante (es. NewMemoryNetwork().Listen nei test) viene chiuso da Shutdown
+	Transport Transport
+
+	// Orologio di ticker, timeout, backoff e timestamp LastSeen (opzionale): nil = orologio
+	// reale. Test e simulatore usano un orologio virtuale per far avanzare il tempo istantaneamente
+	Clock Clock
+}
+
+// ✅ Configurazione di default (identità e porta vanno sempre impostate dal chiamante)
+func DefaultConfig() Config {
+	gossipConfig := gossip.DefaultConfig()
+	failureConfig := failure.DefaultConfig()
+	joinConfig := join.DefaultConfig()
+
+	return Config{
+		GossipInterval:       gossipConfig.Interval,
+		GossipFanout:         gossipConfig.Fanout,
+		PushPullInterval:     gossipConfig.PushPullInterval,
+		FailureDetector:      failureConfig.Detector,
+		PhiThreshold:         failureConfig.PhiThreshold,
+		FailureCheckInterval: failureConfig.CheckInterval,
+		DeadTimeout:          failureConfig.DeadTimeout,
+		TombstoneRetention:   failureConfig.TombstoneRetention,
+		ProbeInterval:        failureConfig.ProbeInterval,
+		ProbeTimeout:         failureConfig.ProbeTimeout,
+		IndirectChecks:       failureConfig.IndirectChecks,
+		JoinTimeout:          joinConfig.Timeout,
+		JoinInitialBackoff:   joinConfig.InitialBackoff,
+		JoinMaxBackoff:       joinConfig.MaxBackoff,
+	}
+}
+
+// ✅ Controlla che tutti i valori siano coerenti
+func (c Config) Validate() error {
+	if c.Name == "" || c.Port == "" {
+		return errors.New("Name e Port devono essere specificati")
+	}
+	if !validPort(c.Port) {
+		return fmt.Errorf("porta non valida: %s", c.Port)
+	}
+	switch {
+	case c.IP != "" && !validAdvertiseHost(c.IP):
+		return fmt.Errorf("IP annunciato non valido (atteso un IP raggiungibile o un hostname): %s", c.IP)
+	case c.IP != "" && c.DiscoverIP:
+		return errors.New("DiscoverIP richiede IP vuoto: l'indirizzo annunciato è già configurato")
+	case c.IP == "" && !c.DiscoverIP:
+		return errors.New("IP deve essere specificato, oppure DiscoverIP per scoprirlo durante il JOIN")
+	}
+	if c.BindIP != "" && net.ParseIP(c.BindIP) == nil {
+		return fmt.Errorf("BindIP non valido: %s", c.BindIP)
+	}
+	if c.BindPort != "" && !validPort(c.BindPort) {
+		return fmt.Errorf("BindPort non valida: %s", c.BindPort)
+	}
+
+	if c.GossipInterval <= 0 || c.PushPullInterval <= 0 {
+		return errors.New("GossipInterval e PushPullInterval devono essere positivi")
+	}
+	if c.GossipFanout <= 0 {
+		return fmt.Errorf("GossipFanout non valido: %d", c.GossipFanout)
+	}
+
+	switch c.FailureDetector {
+	case DetectorTimeout, DetectorPhi:
+	default:
+		return fmt.Errorf("FailureDetector non valido: %s (valori ammessi: timeout, phi)", c.FailureDetector)
+	}
+	if c.PhiThreshold <= 0 {
+		return fmt.Errorf("PhiThreshold non valido: %v", c.PhiThreshold)
+	}
+	if c.FailureCheckInterval <= 0 || c.DeadTimeout <= 0 || c.TombstoneRetention <= 0 {
+		return errors.New("FailureCheckInterval, DeadTimeout e TombstoneRetention devono essere positivi")
+	}
+	if c.ProbeTimeout <= 0 || c.ProbeTimeout >= c.ProbeInterval {
+		return errors.New("ProbeTimeout deve essere positivo e minore di ProbeInterval")
+	}
+	if c.IndirectChecks < 0 {
+		return fmt.Errorf("IndirectChecks non valido: %d", c.IndirectChecks)
+	}
+	if c.JoinTimeout <= 0 || c.JoinInitialBackoff <= 0 || c.JoinMaxBackoff < c.JoinInitialBackoff {
+		return errors.New("JoinTimeout e JoinInitialBackoff devono essere positivi e JoinMaxBackoff non inferiore a JoinInitialBackoff")
+	}
+	if c.IdentityKey != nil || len(c.TrustedKeys) > 0 {
+		if c.IdentityKey == nil || len(c.TrustedKeys) == 0 || c.IdentityCertificate == nil {
+			return errors.New("IdentityKey, IdentityCertificate e TrustedKeys vanno specificati insieme")
+		}
+		if c.IP == "" {
+			return errors.New("con le identità attive IP deve essere specificato (le entry firmate non possono cambiare indirizzo)")
+		}
+	}
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" || c.TLSCAFile != "" {
+		if c.TLSCertFile == "" || c.TLSKeyFile == "" || c.TLSCAFile == "" {
+			return errors.New("TLSCertFile, TLSKeyFile e TLSCAFile vanno specificati insieme")
+		}
+	}
+	for _, key := range c.EncryptionKeys {
+		if err := keyring.ValidateKey(key); err != nil {
+			return fmt.Errorf("EncryptionKeys: %v", err)
+		}
+	}
+	return nil
+}
+
+// ✅ Indirizzo annunciabile: un IP specifico (non 0.0.0.0 o :: né multicast) oppure un hostname
+func validAdvertiseHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return !ip.IsUnspecified() && !ip.IsMulticast()
+	}
+	if len(host) > 253 || strings.HasPrefix(host, ".") || strings.HasSuffix(host, ".") {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// ✅ Indirizzo locale su cui aprire la porta (UDP e TCP)
+func (c Config) bindAddress() string {
+	port := c.BindPort
+	if port == "" {
+		port = c.Port
+	}
+	return net.JoinHostPort(c.BindIP, port)
+}
+
+// ✅ File del TLS mutuo (nil se non configurato)
+func (c Config) tlsFiles() *transport.TLSFiles {
+	if c.TLSCertFile == "" {
+		return nil
+	}
+	return &transport.TLSFiles{
+		CertFile: c.TLSCertFile,
+		KeyFile:  c.TLSKeyFile,
+		CAFile:   c.TLSCAFile,
+	}
+}
+
+// ✅ Parametri per il ciclo di gossip interno
+func (c Config) gossipConfig() gossip.Config {
+	return gossip.Config{
+		Interval:         c.GossipInterval,
+		Fanout:           c.GossipFanout,
+		PushPullInterval: c.PushPullInterval,
+		Clock:            c.clock(),
+	}
+}
+
+// ✅ Parametri per failure detector e Prober interni
+func (c Config) failureConfig() failure.Config {
+	return failure.Config{
+		Detector:           c.FailureDetector,
+		PhiThreshold:       c.PhiThreshold,
+		CheckInterval:      c.FailureCheckInterval,
+		DeadTimeout:        c.DeadTimeout,
+		TombstoneRetention: c.TombstoneRetention,
+		ProbeInterval:      c.ProbeInterval,
+		ProbeTimeout:       c.ProbeTimeout,
+		IndirectChecks:     c.IndirectChecks,
+		Clock:              c.clock(),
+	}
+}
+
+// ✅ Parametri per il JOIN verso i seed
+func (c Config) joinConfig() join.Config {
+	config := join.DefaultConfig()
+	config.Timeout = c.JoinTimeout
+	config.InitialBackoff = c.JoinInitialBackoff
+	config.MaxBackoff = c.JoinMaxBackoff
+	config.Clock = c.clock()
+	return config
+}
+
+// ✅ Orologio del nodo (reale se non configurato)
+func (c Config) clock() clock.Clock {
+	if c.Clock == nil {
+		return clock.Real
+	}
+	return c.Clock
+}
@@ -0,0 +1,86 @@
+package memberlist
+
+import "Gossip/internal/util"
+
+// ✅ EventDelegate riceve le transizioni di stato dei membri del cluster.
+// Ogni transizione viene notificata una sola volta e nell'ordine in cui è avvenuta
+// (mai con chiamate concorrenti); i metodi sono chiamati dalle goroutine interne
+// del nodo e devono quindi ritornare rapidamente.
+type EventDelegate interface {
+	NotifyJoin(member Member)    // Membro nuovo (o tornato ALIVE dopo DEAD/LEFT)
+	NotifyUpdate(member Member)  // Membro ALIVE aggiornato (nuova incarnation, indirizzo, sospetto confutato)
+	NotifySuspect(member Member) // Membro sospettato di guasto
+	NotifyLeave(member Member)   // Membro uscito volontariamente
+	NotifyDead(member Member)    // Membro dichiarato guasto
+}
+
+// Tipo di evento consegnato da ChannelEventDelegate
+type EventType int
+
+const (
+	EventJoin EventType = iota
+	EventUpdate
+	EventSuspect
+	EventLeave
+	EventDead
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventJoin:
+		return "join"
+	case EventUpdate:
+		return "update"
+	case EventSuspect:
+		return "suspect"
+	case EventLeave:
+		return "leave"
+	case EventDead:
+		return "dead"
+	}
+	return "unknown"
+}
+
+// ✅ Evento di membership consegnato su canale
+type MemberEvent struct {
+	Type   EventType
+	Member Member
+}
+
+// ✅ ChannelEventDelegate: EventDelegate che inoltra gli eventi su un canale.
+// L'invio è bloccante: il canale va letto con continuità (o avere un buffer
+// adeguato), altrimenti si bloccano gossip e failure detection.
+type ChannelEventDelegate struct {
+	Ch chan<- MemberEvent
+}
+
+func (c *ChannelEventDelegate) NotifyJoin(member Member) {
+	c.Ch <- MemberEvent{Type: EventJoin, Member: member}
+}
+
+func (c *ChannelEventDelegate) NotifyUpdate(member Member) {
+	c.Ch <- MemberEvent{Type: EventUpdate, Member: member}
+}
+
+func (c *ChannelEventDelegate) NotifySuspect(member Member) {
+	c.Ch <- MemberEvent{Type: EventSuspect, Member: member}
+}
+
+func (c *ChannelEventDelegate) NotifyLeave(member Member) {
+	c.Ch <- MemberEvent{Type: EventLeave, Member: member}
+}
+
+func (c *ChannelEventDelegate) NotifyDead(member Member) {
+	c.Ch <- MemberEvent{Type: EventDead, Member: member}
+}
+
+// ✅ Adatta un EventDelegate pubblico a quello interno della Membership List
+type eventAdapter struct {
+	delegate EventDelegate
+}
+
+func (a eventAdapter) NotifyJoin(node util.NodeStatus)    { a.delegate.NotifyJoin(toMember(node)) }
+func (a eventAdapter) NotifyUpdate(node util.NodeStatus)  { a.delegate.NotifyUpdate(toMember(node)) }
+func (a eventAdapter) NotifySuspect(node util.NodeStatus) { a.delegate.NotifySuspect(toMember(node)) }
+func (a eventAdapter) NotifyLeave(node util.NodeStatus)   { a.delegate.NotifyLeave(toMember(node)) }
+func (a eventAdapter) NotifyDead(node util.NodeStatus)    { a.delegate.NotifyDead(toMember(node)) }
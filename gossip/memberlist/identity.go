@@ -0,0 +1,18 @@
+package memberlist
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+
+	"Gossip/internal/identity"
+)
+
+// ✅ Genera una coppia di chiavi Ed25519 (per una CA o per l'identità di un nodo)
+func GenerateIdentityKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// ✅ Certificato per il nodo name (Config.IdentityCertificate), firmato con la chiave della CA
+func IssueCertificate(caKey ed25519.PrivateKey, name string, nodeKey ed25519.PublicKey) []byte {
+	return identity.Certify(caKey, name, nodeKey)
+}
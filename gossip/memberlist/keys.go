@@ -0,0 +1,79 @@
+package memberlist
+
+import (
+	"errors"
+	"fmt"
+
+	"Gossip/internal/keyring"
+	"Gossip/internal/rotation"
+	"Gossip/internal/util"
+)
+
+// ✅ Esito di un'operazione sulle chiavi propagata a tutto il cluster
+type KeyResponse struct {
+	NumNodes int               // Membri contattati, incluso il nodo locale
+	NumErr   int               // Membri su cui l'operazione è fallita
+	Messages map[string]string // Errore per membro (solo per i membri falliti)
+	Keys     map[string]int    // Chiave (base64) → numero di membri che la hanno installata
+}
+
+// ✅ Installa una chiave su tutti i membri: viene accettata in decifratura ma non usata per cifrare.
+// Primo passo di una rotazione: InstallKey(nuova), UseKey(nuova), RemoveKey(vecchia)
+func (n *Node) InstallKey(key []byte) (*KeyResponse, error) {
+	if err := keyring.ValidateKey(key); err != nil {
+		return nil, err
+	}
+	return n.keyOperation(rotation.OpInstall, key)
+}
+
+// ✅ Rende primaria su tutti i membri una chiave già installata ovunque
+func (n *Node) UseKey(key []byte) (*KeyResponse, error) {
+	return n.keyOperation(rotation.OpUse, key)
+}
+
+// ✅ Rimuove una chiave da tutti i membri (non può essere la primaria). Viene rifiutata, senza
+// modificare nessun nodo, se un membro non risponde o non usa ancora la stessa chiave primaria
+func (n *Node) RemoveKey(key []byte) (*KeyResponse, error) {
+	return n.keyOperation(rotation.OpRemove, key)
+}
+
+// ✅ Elenca le chiavi installate su ogni membro (KeyResponse.Keys)
+func (n *Node) ListKeys() (*KeyResponse, error) {
+	return n.keyOperation(rotation.OpList, nil)
+}
+
+// ✅ Esegue l'operazione su tutti i membri attivi e ne riassume l'esito.
+// Restituisce un errore anche se l'operazione è fallita solo su alcuni membri
+func (n *Node) keyOperation(op string, key []byte) (*KeyResponse, error) {
+	ctx, err := n.runningContext()
+	if err != nil {
+		return nil, err
+	}
+	if n.keys == nil {
+		return nil, errors.New("cifratura non attiva: configurare EncryptionKeys")
+	}
+
+	response := summarizeKeyResults(n.keys.Execute(ctx, op, key))
+	if response.NumErr > 0 {
+		return response, fmt.Errorf("operazione %s fallita su %d membri su %d", op, response.NumErr, response.NumNodes)
+	}
+	return response, nil
+}
+
+func summarizeKeyResults(results map[string]util.KeyResult) *KeyResponse {
+	response := &KeyResponse{
+		NumNodes: len(results),
+		Messages: make(map[string]string),
+		Keys:     make(map[string]int),
+	}
+	for id, result := range results {
+		if result.Error != "" {
+			response.NumErr++
+			response.Messages[id] = result.Error
+		}
+		for _, key := range result.Keys {
+			response.Keys[key]++
+		}
+	}
+	return response
+}
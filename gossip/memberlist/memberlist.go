@@ -0,0 +1,396 @@
+// Package memberlist espone il nodo gossip (membership, failure detection,
+// join/leave) come libreria embeddabile in altri servizi Go.
+package memberlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"Gossip/internal/failure"
+	"Gossip/internal/gossip"
+	"Gossip/internal/identity"
+	"Gossip/internal/join"
+	"Gossip/internal/keyring"
+	"Gossip/internal/leave"
+	"Gossip/internal/membership"
+	"Gossip/internal/rotation"
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// Errori di Join definitivi (ritentare non serve)
+var (
+	ErrNameConflict     = join.ErrNameConflict     // Config.Name è già usato da un altro membro attivo
+	ErrIdentityRejected = join.ErrIdentityRejected // Entry non firmata o certificato non emesso da una CA fidata
+)
+
+// Errore delle operazioni chiamate dopo Shutdown (un nodo arrestato non può ripartire)
+var ErrStopped = errors.New("nodo arrestato")
+
+// ✅ Stato di un membro del cluster visto dal nodo locale
+type Member struct {
+	ID          string // Nome univoco e stabile del membro (Config.Name)
+	IP          string // Indirizzo IP/hostname annunciato
+	Port        string // Porta annunciata (UDP e TCP)
+	Status      string // alive, suspect, dead, left
+	Incarnation uint64 // Numero di incarnazione del membro
+}
+
+// ✅ Node: un membro del cluster gossip avviabile all'interno di un processo
+type Node struct {
+	config     Config
+	self       util.NodeStatus
+	membership *membership.MembershipList
+	keyring    *keyring.Keyring // nil se il traffico non è cifrato
+	verifier   *gossip.SenderVerifier
+
+	mutex     sync.Mutex
+	transport transport.Transport
+	prober    *failure.Prober
+	joiner    *join.Joiner
+	keys      *rotation.Manager // nil se il traffico non è cifrato
+	tls       *transport.TLS    // nil se il TLS mutuo non è configurato
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	started   bool
+	stopped   bool
+}
+
+// ✅ Crea un nuovo Node (non ancora in ascolto: vedi Start)
+func Create(config Config) (*Node, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("configurazione non valida: %v", err)
+	}
+
+	self := util.NodeStatus{
+		ID:     config.Name, // Nome stabile: l'indirizzo può cambiare tra un riavvio e l'altro
+		IP:     config.IP,
+		Port:   config.Port,
+		Status: "alive",
+	}
+
+	localMembership := membership.NewMembershipList(self.ID, config.clock())
+	if config.IdentityKey != nil {
+		nodeIdentity, err := identity.New(config.Name, config.IdentityKey, config.IdentityCertificate, config.TrustedKeys)
+		if err != nil {
+			return nil, fmt.Errorf("configurazione non valida: %v", err)
+		}
+		localMembership.SetSigner(nodeIdentity)
+	}
+	// ✅ Con il keyring solo i membri possono inviare messaggi: le dichiarazioni su sé stessi sono autentiche
+	localMembership.SetTrustedClaims(len(config.EncryptionKeys) > 0 || config.InsecureSelfClaims)
+	if config.Events != nil {
+		localMembership.SetEventDelegate(eventAdapter{delegate: config.Events})
+	}
+	localMembership.AddOrUpdateNode(self)
+
+	var nodeKeyring *keyring.Keyring
+	if len(config.EncryptionKeys) > 0 {
+		var err error
+		nodeKeyring, err = keyring.New(config.EncryptionKeys)
+		if err != nil {
+			return nil, fmt.Errorf("configurazione non valida: %v", err)
+		}
+	}
+
+	return &Node{
+		config:     config,
+		self:       self,
+		membership: localMembership,
+		keyring:    nodeKeyring,
+		verifier:   gossip.NewSenderVerifier(localMembership),
+	}, nil
+}
+
+// ✅ Apre la porta del nodo (UDP e TCP, unica per invii e ricezioni) e avvia server, ciclo di gossip,
+// Prober e failure detector. Tutti i cicli si fermano alla cancellazione di ctx oppure con Shutdown
+func (n *Node) Start(ctx context.Context) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.started {
+		return errors.New("nodo già avviato")
+	}
+
+	nodeTransport := n.config.Transport
+	if nodeTransport == nil {
+		netTransport, err := transport.Listen(n.config.bindAddress())
+		if err != nil {
+			return fmt.Errorf("errore apertura porta %s: %v", n.config.bindAddress(), err)
+		}
+		nodeTransport = netTransport
+	}
+	if files := n.config.tlsFiles(); files != nil {
+		// ✅ Stream protetti da TLS mutuo (i pacchetti UDP restano affidati al keyring)
+		tlsTransport, err := transport.NewTLS(nodeTransport, *files, n.memberName)
+		if err != nil {
+			if n.config.Transport == nil {
+				nodeTransport.Close() // Porta aperta qui sopra
+			}
+			return err
+		}
+		nodeTransport = tlsTransport
+		n.tls = tlsTransport
+		util.Info("[BOOTSTRAP] TLS mutuo attivo sugli stream TCP.")
+	}
+	if n.keyring != nil {
+		// ✅ Tutto il traffico (pacchetti e stream) viene cifrato con il keyring
+		nodeTransport = transport.NewEncrypted(nodeTransport, n.keyring)
+		util.Info(fmt.Sprintf("[BOOTSTRAP] Cifratura AES-GCM attiva (%d chiavi installate).", len(n.keyring.Keys())))
+	}
+
+	if !n.membership.SelfClaimsAuthenticated() {
+		// ✅ Configurazione valida ma con un effetto poco evidente: Leave non fa uscire il nodo
+		util.Warn("[BOOTSTRAP] Né EncryptionKeys né identità configurate: LEAVE e confutazioni ALIVE verranno rifiutati " +
+			"e l'uscita dei nodi sarà rilevata solo dal failure detector (InsecureSelfClaims li accetta, solo su reti fidate).")
+	}
+
+	failureConfig := n.config.failureConfig()
+	var phiDetector *failure.PhiDetector
+	if failureConfig.Detector == failure.DetectorPhi {
+		phiDetector = failure.NewPhiDetector(failureConfig.PhiThreshold, failureConfig.Clock)
+		util.Info(fmt.Sprintf("[BOOTSTRAP] Failure detector phi-accrual (soglia %.2f).", failureConfig.PhiThreshold))
+	} else {
+		util.Info("[BOOTSTRAP] Failure detector a soglie fisse.")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	n.transport = nodeTransport
+	n.prober = failure.NewProber(failureConfig, nodeTransport, n.membership, n.self, phiDetector)
+	n.joiner = join.NewJoiner(n.config.joinConfig(), nodeTransport, n.membership, n.prober)
+	if n.keyring != nil {
+		n.keys = rotation.NewManager(n.keyring, nodeTransport, n.membership, n.config.clock())
+	}
+	n.ctx = ctx
+	n.cancel = cancel
+	n.started = true
+
+	n.run(func() { gossip.StartUDPServer(nodeTransport, n.membership, n.self, n.prober, n.verifier) })
+	n.run(func() {
+		gossip.StartStreamServer(n.config.clock(), nodeTransport, n.membership, n.joiner, n.keys, n.verifier)
+	})
+	n.run(func() {
+		gossip.StartGossipCycle(ctx, n.config.gossipConfig(), nodeTransport, n.membership, n.self, n.verifier)
+	})
+	n.run(func() { failure.StartFailureDetector(ctx, failureConfig, n.membership, n.self, phiDetector) })
+	n.run(func() { n.prober.Start(ctx) })
+
+	// La chiusura della porta ferma i server UDP e TCP
+	n.run(func() {
+		<-ctx.Done()
+		nodeTransport.Close()
+	})
+
+	util.Info(fmt.Sprintf("[BOOTSTRAP] Nodo %s avviato (in ascolto su %s, annunciato come %s).", n.self.ID, nodeTransport.LocalAddr(), n.self.Address()))
+	return nil
+}
+
+// ✅ Rilegge dai file certificato, chiave e CA del TLS mutuo (es. alla ricezione di SIGHUP).
+// Le connessioni successive usano i nuovi file; in caso di errore restano in uso i precedenti
+func (n *Node) ReloadTLS() error {
+	n.mutex.Lock()
+	tlsTransport := n.tls
+	n.mutex.Unlock()
+
+	if tlsTransport == nil {
+		return errors.New("TLS non attivo: configurare TLSCertFile, TLSKeyFile e TLSCAFile")
+	}
+	if err := tlsTransport.Reload(); err != nil {
+		return err
+	}
+	util.Info("[BOOTSTRAP] Certificati TLS ricaricati.")
+	return nil
+}
+
+// ✅ Nome del membro in ascolto su address, atteso nel suo certificato TLS ("" se sconosciuto)
+func (n *Node) memberName(address string) string {
+	for _, node := range n.membership.GetCopy() {
+		if !membership.IsTombstone(node.Status) && node.Address() == address {
+			return node.ID
+		}
+	}
+	return ""
+}
+
+// ✅ Esegue una goroutine tracciata dal WaitGroup del nodo
+func (n *Node) run(fn func()) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		fn()
+	}()
+}
+
+// ✅ Entra nel cluster tramite i seed "ip:port": le richieste JOIN partono in parallelo
+// con backoff esponenziale e Join ritorna alla prima JOIN_ACK (o allo scadere di
+// Config.JoinTimeout). Restituisce il numero di seed che hanno risposto.
+// Il nodo deve essere già avviato con Start, perché le risposte arrivano sulla sua porta
+func (n *Node) Join(seeds []string) (int, error) {
+	ctx, err := n.runningContext()
+	if err != nil {
+		return 0, err
+	}
+
+	// I seed restano fuori dalla Membership List finché non rispondono
+	pending := n.membership.AddBootstrapAddresses(seeds)
+	util.Debug(fmt.Sprintf("[BOOTSTRAP] %d SEED_NODES in attesa di verifica.", pending))
+
+	joined, err := n.joiner.JoinCluster(ctx, seeds)
+	if err != nil {
+		return 0, err
+	}
+	util.Info(fmt.Sprintf("[JOIN] Nodo %s entrato nel cluster (%d seed hanno risposto).", n.self.ID, joined))
+	return joined, nil
+}
+
+// ✅ Come Join, ma in background: ripete il JOIN finché un seed risponde o il nodo viene arrestato
+func (n *Node) JoinInBackground(seeds []string) error {
+	// ✅ La goroutine va registrata nel WaitGroup sotto il mutex: altrimenti uno Shutdown
+	// concorrente potrebbe già essere in wg.Wait() (Add durante Wait è una race)
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if err := n.checkRunning(); err != nil {
+		return err
+	}
+	ctx := n.ctx
+
+	n.run(func() {
+		for {
+			_, err := n.Join(seeds)
+			if err == nil {
+				return
+			}
+			if errors.Is(err, ErrNameConflict) || errors.Is(err, ErrIdentityRejected) {
+				util.Warn(fmt.Sprintf("[JOIN] JOIN abbandonato: %v", err))
+				return
+			}
+
+			// ✅ I tentativi successivi contattano solo i seed non ancora verificati: quelli
+			// entrati nel frattempo nella Membership List (es. perché ci hanno sondato) sono
+			// già membri, e se lo sono tutti il nodo fa già parte del cluster
+			seeds = n.membership.BootstrapAddresses()
+			if len(seeds) == 0 {
+				util.Info("[JOIN] Tutti i seed sono già membri noti: JOIN in background concluso")
+				return
+			}
+			util.Warn(fmt.Sprintf("[JOIN] JOIN fallito, nuovo tentativo tra %v verso %d seed: %v", n.config.JoinMaxBackoff, len(seeds), err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-n.config.clock().After(n.config.JoinMaxBackoff):
+			}
+		}
+	})
+	return nil
+}
+
+// ✅ Contesto dei cicli del nodo (errore se il nodo non è in esecuzione)
+func (n *Node) runningContext() (context.Context, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if err := n.checkRunning(); err != nil {
+		return nil, err
+	}
+	return n.ctx, nil
+}
+
+// ✅ Errore se il nodo non è ancora avviato o è già stato arrestato (da chiamare sotto il mutex)
+func (n *Node) checkRunning() error {
+	switch {
+	case n.stopped:
+		return ErrStopped
+	case !n.started:
+		return errors.New("nodo non in esecuzione: chiamare prima Start")
+	}
+	return nil
+}
+
+// ✅ Comunica l'uscita volontaria a tutti i membri raggiungibili, attendendo al massimo timeout.
+// Il nodo resta LEFT anche per sé stesso: smette di fare gossip e probe finché non viene arrestato
+func (n *Node) Leave(timeout time.Duration) error {
+	// ✅ L'invio è una goroutine del nodo: si interrompe allo scadere del timeout o con
+	// Shutdown, che ne attende la fine prima di ritornare
+	n.mutex.Lock()
+	if err := n.checkRunning(); err != nil {
+		n.mutex.Unlock()
+		return err
+	}
+	ctx, cancel := context.WithCancel(n.ctx)
+	defer cancel()
+	done := make(chan struct{})
+	n.run(func() {
+		defer close(done)
+		leave.SendLeaveMessage(ctx, n.transport, n.membership, n.self)
+	})
+	n.mutex.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-n.config.clock().After(timeout):
+		return fmt.Errorf("timeout invio LEAVE dopo %v", timeout)
+	}
+}
+
+// ✅ Membri attualmente attivi (alive e suspect), ordinati per ID, incluso il nodo locale
+func (n *Node) Members() []Member {
+	members := []Member{}
+	for _, node := range n.membership.GetCopy() {
+		if !membership.IsTombstone(node.Status) {
+			members = append(members, toMember(node))
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+	return members
+}
+
+// ✅ Messaggi rifiutati, per tipo: LEAVE e ALIVE malformati o con un mittente non autentico
+// ("leave", "alive"), pacchetti non decifrabili ("undecryptable", solo con EncryptionKeys) ed
+// entry con firma non valida ("entry", solo con le identità attive)
+func (n *Node) RejectedMessages() map[string]uint64 {
+	rejected := n.verifier.Rejected()
+	if entries := n.membership.RejectedEntries(); entries > 0 {
+		rejected["entry"] = entries
+	}
+	return rejected
+}
+
+// ✅ Stato corrente del nodo locale
+func (n *Node) LocalNode() Member {
+	return toMember(n.membership.Self())
+}
+
+// ✅ Ferma tutti i cicli del nodo e chiude la porta (senza inviare LEAVE: vedi Leave)
+func (n *Node) Shutdown() error {
+	n.mutex.Lock()
+	if !n.started || n.stopped {
+		n.mutex.Unlock()
+		return nil
+	}
+	n.stopped = true
+	n.cancel()
+	n.mutex.Unlock()
+
+	n.wg.Wait()
+	util.Info(fmt.Sprintf("[EXIT] Nodo %s arrestato.", n.self.ID))
+	return nil
+}
+
+func toMember(node util.NodeStatus) Member {
+	return Member{
+		ID:          node.ID,
+		IP:          node.IP,
+		Port:        node.Port,
+		Status:      node.Status,
+		Incarnation: node.Incarnation,
+	}
+}
@@ -0,0 +1,885 @@
+package memberlist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Gossip/internal/transport"
+	"Gossip/internal/util"
+)
+
+// Test di integrazione: nodi reali su porte di loopback nello stesso processo.
+// Le attese derivano dai timer configurati in testConfig.
+
+func TestMain(m *testing.M) {
+	// I nodi scrivono molti log: nei test interessano solo le asserzioni
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// ✅ Configurazione con timer brevi, per scenari di pochi secondi
+func testConfig(name string, port int) Config {
+	config := DefaultConfig()
+	config.Name = name
+	config.IP = "127.0.0.1"
+	config.Port = strconv.Itoa(port)
+	config.GossipInterval = 50 * time.Millisecond
+	config.PushPullInterval = 500 * time.Millisecond
+	config.ProbeInterval = 100 * time.Millisecond
+	config.ProbeTimeout = 40 * time.Millisecond
+	config.FailureCheckInterval = 100 * time.Millisecond
+	config.DeadTimeout = 1 * time.Second
+	config.TombstoneRetention = 2 * time.Second
+	config.JoinTimeout = 5 * time.Second
+	config.JoinInitialBackoff = 50 * time.Millisecond
+	config.JoinMaxBackoff = 500 * time.Millisecond
+	// Loopback senza processi estranei: i test delle uscite volontarie non richiedono il keyring
+	// (la configurazione di default è verificata da TestUnauthenticatedLeaveRejected)
+	config.InsecureSelfClaims = true
+	return config
+}
+
+// ✅ Attesa massima perché un cambiamento raggiunga tutti i nodi: qualche round di
+// gossip più un push-pull completo (che recupera i rumour persi)
+func convergeTimeout(config Config, nodes int) time.Duration {
+	return 2*config.PushPullInterval + time.Duration(nodes)*10*config.GossipInterval
+}
+
+// ✅ Attesa massima perché un nodo guasto venga sospettato da tutti: ogni nodo sonda un
+// membro per ProbeInterval, quindi entro un giro completo qualcuno ha sondato il guasto
+func suspectTimeout(config Config, nodes int) time.Duration {
+	return time.Duration(nodes)*config.ProbeInterval + convergeTimeout(config, nodes)
+}
+
+// ✅ Porta di loopback libera (UDP e TCP vengono aperti sullo stesso numero)
+func freePort(t *testing.T) int {
+	t.Helper()
+	for attempt := 0; attempt < 10; attempt++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("porta libera: %v", err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+
+		packetConn, err := net.ListenPacket("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err == nil {
+			packetConn.Close()
+			return port
+		}
+	}
+	t.Fatal("nessuna porta libera per UDP e TCP")
+	return 0
+}
+
+// ✅ Crea e avvia un nodo (arrestato alla fine del test)
+func startNode(t *testing.T, config Config) *Node {
+	t.Helper()
+	node, err := Create(config)
+	if err != nil {
+		t.Fatalf("Create %s: %v", config.Name, err)
+	}
+	if err := node.Start(context.Background()); err != nil {
+		t.Fatalf("Start %s: %v", config.Name, err)
+	}
+	t.Cleanup(func() { node.Shutdown() })
+	return node
+}
+
+// ✅ Avvia un cluster di n nodi (node1..nodeN) entrati tramite il primo
+func startCluster(t *testing.T, n int) ([]*Node, Config) {
+	t.Helper()
+	nodes, config, _ := startClusterWith(t, n, func(name string) Config {
+		return testConfig(name, freePort(t))
+	})
+	return nodes, config
+}
+
+// ✅ Avvia un cluster di n nodi con le configurazioni create da newConfig; restituisce anche il seed
+func startClusterWith(t *testing.T, n int, newConfig func(name string) Config) ([]*Node, Config, string) {
+	t.Helper()
+	nodes := []*Node{}
+	var config Config
+	var seed string
+	for i := 1; i <= n; i++ {
+		config = newConfig(fmt.Sprintf("node%d", i))
+		node := startNode(t, config)
+		if i == 1 {
+			seed = net.JoinHostPort(config.IP, config.Port)
+		} else if _, err := node.Join([]string{seed}); err != nil {
+			t.Fatalf("Join %s: %v", config.Name, err)
+		}
+		nodes = append(nodes, node)
+	}
+	waitForMembers(t, nodes, n, convergeTimeout(config, n))
+	return nodes, config, seed
+}
+
+// ✅ Attende che condition diventi vera entro timeout
+func waitFor(t *testing.T, timeout time.Duration, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout dopo %v: %s", timeout, description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// ✅ Attende che ogni nodo veda esattamente count membri attivi, tutti ALIVE
+func waitForMembers(t *testing.T, nodes []*Node, count int, timeout time.Duration) {
+	t.Helper()
+	waitFor(t, timeout, fmt.Sprintf("ogni nodo deve vedere %d membri alive", count), func() bool {
+		for _, node := range nodes {
+			members := node.Members()
+			if len(members) != count {
+				return false
+			}
+			for _, member := range members {
+				if member.Status != "alive" {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+// ✅ Attende che nella Membership List di ogni nodo id abbia lo stato atteso ("" = rimosso)
+func waitForStatus(t *testing.T, nodes []*Node, id, status string, timeout time.Duration) {
+	t.Helper()
+	waitFor(t, timeout, fmt.Sprintf("%s deve essere %q per tutti", id, status), func() bool {
+		for _, node := range nodes {
+			current, exists := node.membership.GetNodeStatus(id)
+			if status == "" && exists || status != "" && current != status {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// ✅ Invia datagrammi UDP a addr da un socket estraneo al cluster (porta diversa da ogni membro)
+func sendUDP(t *testing.T, addr string, packets ...string) {
+	t.Helper()
+	attacker, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	defer attacker.Close()
+	target, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		t.Fatalf("indirizzo: %v", err)
+	}
+	for _, packet := range packets {
+		if _, err := attacker.WriteTo([]byte(packet), target); err != nil {
+			t.Fatalf("invio: %v", err)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	nodes, config := startCluster(t, 3)
+
+	// Un nuovo nodo entra tramite un seed qualsiasi e tutti lo vedono
+	joinConfig := testConfig("node4", freePort(t))
+	newNode := startNode(t, joinConfig)
+	seed := nodes[2].LocalNode()
+	joined, err := newNode.Join([]string{net.JoinHostPort(seed.IP, seed.Port)})
+	if err != nil || joined != 1 {
+		t.Fatalf("Join: %d seed, errore %v", joined, err)
+	}
+
+	nodes = append(nodes, newNode)
+	waitForMembers(t, nodes, 4, convergeTimeout(config, 4))
+	for _, node := range nodes {
+		if _, exists := node.membership.GetNode("node4"); !exists {
+			t.Errorf("%s non conosce node4", node.LocalNode().ID)
+		}
+	}
+}
+
+// ✅ Orologio reale implementato solo con l'API pubblica, come farebbe un'applicazione esterna;
+// conta i ticker creati per verificare che il nodo lo usi davvero
+type countingClock struct {
+	tickers atomic.Int64
+}
+
+func (c *countingClock) Now() time.Time                         { return time.Now() }
+func (c *countingClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (c *countingClock) NewTicker(d time.Duration) *Ticker {
+	c.tickers.Add(1)
+	ticker := time.NewTicker(d)
+	return NewTicker(ticker.C, ticker.Stop)
+}
+
+func TestCustomClock(t *testing.T) {
+	nodes, config := startCluster(t, 2)
+
+	clk := &countingClock{}
+	clockConfig := testConfig("node3", freePort(t))
+	clockConfig.Clock = clk
+	newNode := startNode(t, clockConfig)
+	seed := nodes[0].LocalNode()
+	if _, err := newNode.Join([]string{net.JoinHostPort(seed.IP, seed.Port)}); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+
+	waitForMembers(t, append(nodes, newNode), 3, convergeTimeout(config, 3))
+	if clk.tickers.Load() == 0 {
+		t.Fatal("il nodo non ha usato l'orologio configurato")
+	}
+}
+
+func TestJoinNameConflict(t *testing.T) {
+	nodes, _ := startCluster(t, 2)
+
+	// Un secondo processo con il nome di un membro attivo viene rifiutato
+	duplicate := startNode(t, testConfig("node2", freePort(t)))
+	seed := nodes[0].LocalNode()
+	if _, err := duplicate.Join([]string{net.JoinHostPort(seed.IP, seed.Port)}); !errors.Is(err, ErrNameConflict) {
+		t.Fatalf("atteso ErrNameConflict, ottenuto %v", err)
+	}
+}
+
+func TestJoinInBackgroundAfterShutdown(t *testing.T) {
+	node := startNode(t, testConfig("node1", freePort(t)))
+	node.Shutdown()
+
+	// Dopo Shutdown nessuna goroutine può più essere avviata
+	if err := node.JoinInBackground([]string{"127.0.0.1:7999"}); !errors.Is(err, ErrStopped) {
+		t.Fatalf("atteso ErrStopped, ottenuto %v", err)
+	}
+	if _, err := node.Join([]string{"127.0.0.1:7999"}); !errors.Is(err, ErrStopped) {
+		t.Fatalf("atteso ErrStopped, ottenuto %v", err)
+	}
+}
+
+func TestValidateAdvertiseAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		ip         string
+		discoverIP bool
+		bindIP     string
+		wantErr    bool
+	}{
+		{"IP valido", "10.0.0.1", false, "", false},
+		{"IPv6 valido", "fd00::1", false, "", false},
+		{"hostname", "node1.cluster.local", false, "", false},
+		{"IP scoperto durante il JOIN", "", true, "", false},
+		{"IP vuoto senza DiscoverIP", "", false, "", true},
+		{"IP configurato con DiscoverIP", "10.0.0.1", true, "", true},
+		{"IP non specificato", "0.0.0.0", false, "", true},
+		{"IPv6 non specificato", "::", false, "", true},
+		{"IP multicast", "224.0.0.1", false, "", true},
+		{"IP con porta", "10.0.0.1:7946", false, "", true},
+		{"hostname non valido", "nodo uno", false, "", true},
+		{"BindIP non specificato", "10.0.0.1", false, "0.0.0.0", false},
+		{"BindIP non valido", "10.0.0.1", false, "localhost", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig("node1", 7946)
+			config.IP, config.DiscoverIP, config.BindIP = tt.ip, tt.discoverIP, tt.bindIP
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate: %v, errore atteso %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptedCluster(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	first := testConfig("node1", freePort(t))
+	first.EncryptionKeys = [][]byte{key}
+	second := testConfig("node2", freePort(t))
+	second.EncryptionKeys = [][]byte{key}
+
+	nodes := []*Node{startNode(t, first), startNode(t, second)}
+	seed := net.JoinHostPort(first.IP, first.Port)
+	if _, err := nodes[1].Join([]string{seed}); err != nil {
+		t.Fatalf("Join con la stessa chiave: %v", err)
+	}
+	waitForMembers(t, nodes, 2, convergeTimeout(first, 2))
+
+	// Un nodo con un'altra chiave (o senza cifratura) non riesce a entrare
+	for _, keys := range [][][]byte{{[]byte("fedcba9876543210fedcba9876543210")}, nil} {
+		outsider := testConfig("outsider", freePort(t))
+		outsider.EncryptionKeys = keys
+		outsider.JoinTimeout = 500 * time.Millisecond
+		if _, err := startNode(t, outsider).Join([]string{seed}); err == nil {
+			t.Errorf("JOIN riuscito con chiavi %v", keys)
+		}
+	}
+	waitForMembers(t, nodes, 2, convergeTimeout(first, 2))
+
+	// Un LEAVE in chiaro a nome di node2 non viene da chi conosce la chiave: scartato e contato
+	rejected := nodes[0].RejectedMessages()["undecryptable"]
+	sendUDP(t, seed, `{"type":"leave","sender":"node2","node":{"id":"node2","ip":"127.0.0.1","port":"1","status":"left","incarnation":1000}}`)
+	waitFor(t, convergeTimeout(first, 2), "il pacchetto in chiaro deve essere scartato", func() bool {
+		return nodes[0].RejectedMessages()["undecryptable"] > rejected
+	})
+	if status, _ := nodes[0].membership.GetNodeStatus("node2"); status != "alive" {
+		t.Fatalf("node2 dopo il LEAVE in chiaro: %s", status)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := []byte("0123456789abcdef0123456789abcdef")
+	newKey := []byte("fedcba9876543210fedcba9876543210")
+
+	// Cluster cifrato con la vecchia chiave
+	nodes := []*Node{}
+	var config Config
+	var seed string
+	for i := 1; i <= 3; i++ {
+		config = testConfig(fmt.Sprintf("node%d", i), freePort(t))
+		config.EncryptionKeys = [][]byte{oldKey}
+		node := startNode(t, config)
+		if i == 1 {
+			seed = net.JoinHostPort(config.IP, config.Port)
+		} else if _, err := node.Join([]string{seed}); err != nil {
+			t.Fatalf("Join %s: %v", config.Name, err)
+		}
+		nodes = append(nodes, node)
+	}
+	waitForMembers(t, nodes, 3, convergeTimeout(config, 3))
+
+	// La vecchia chiave primaria non può essere rimossa
+	if _, err := nodes[0].RemoveKey(oldKey); err == nil {
+		t.Fatal("RemoveKey della chiave primaria riuscito")
+	}
+
+	// Rotazione da un nodo qualsiasi: install, use, remove
+	if response, err := nodes[1].InstallKey(newKey); err != nil || response.NumNodes != 3 {
+		t.Fatalf("InstallKey: %+v, %v", response, err)
+	}
+	if _, err := nodes[2].UseKey(newKey); err != nil {
+		t.Fatalf("UseKey: %v", err)
+	}
+
+	// Un membro che non ha ricevuto use blocca la rimozione su tutto il cluster
+	nodes[1].keyring.UseKey(oldKey)
+	if response, err := nodes[0].RemoveKey(oldKey); err == nil || response.Keys[base64.StdEncoding.EncodeToString(oldKey)] != 3 {
+		t.Fatalf("RemoveKey con un membro indietro: %+v, %v", response, err)
+	}
+	if _, err := nodes[0].UseKey(newKey); err != nil {
+		t.Fatalf("UseKey ripetuta: %v", err)
+	}
+	if _, err := nodes[0].RemoveKey(oldKey); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+
+	response, err := nodes[0].ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys: %v", err)
+	}
+	encodedNew := base64.StdEncoding.EncodeToString(newKey)
+	if len(response.Keys) != 1 || response.Keys[encodedNew] != 3 {
+		t.Fatalf("chiavi dopo la rotazione: %v", response.Keys)
+	}
+
+	// Il cluster continua a funzionare: entra un nodo che conosce solo la nuova chiave
+	lateConfig := testConfig("node4", freePort(t))
+	lateConfig.EncryptionKeys = [][]byte{newKey}
+	late := startNode(t, lateConfig)
+	if _, err := late.Join([]string{seed}); err != nil {
+		t.Fatalf("Join con la nuova chiave: %v", err)
+	}
+	nodes = append(nodes, late)
+	waitForMembers(t, nodes, 4, convergeTimeout(config, 4))
+}
+
+// ✅ Configurazione con identità certificata da ca
+func signedConfig(t *testing.T, name string, ca ed25519.PrivateKey) Config {
+	t.Helper()
+	publicKey, privateKey, err := GenerateIdentityKey()
+	if err != nil {
+		t.Fatalf("chiave di identità: %v", err)
+	}
+	config := testConfig(name, freePort(t))
+	config.IdentityKey = privateKey
+	config.IdentityCertificate = IssueCertificate(ca, name, publicKey)
+	config.TrustedKeys = []ed25519.PublicKey{ca.Public().(ed25519.PublicKey)}
+	return config
+}
+
+// ✅ Avvia un cluster di n nodi con identità certificate da una nuova CA; restituisce anche il seed
+func startSignedCluster(t *testing.T, n int) ([]*Node, Config, string) {
+	t.Helper()
+	_, ca, _ := GenerateIdentityKey()
+	return startClusterWith(t, n, func(name string) Config {
+		return signedConfig(t, name, ca)
+	})
+}
+
+func TestSignedCluster(t *testing.T) {
+	nodes, config, seed := startSignedCluster(t, 3)
+
+	// Nodi senza identità o certificati da un'altra CA vengono rifiutati
+	_, otherCA, _ := GenerateIdentityKey()
+	for _, outsider := range []Config{testConfig("outsider1", freePort(t)), signedConfig(t, "outsider2", otherCA)} {
+		if _, err := startNode(t, outsider).Join([]string{seed}); !errors.Is(err, ErrIdentityRejected) {
+			t.Errorf("Join di %s: atteso ErrIdentityRejected, ottenuto %v", outsider.Name, err)
+		}
+	}
+
+	// Un membro inventato, diffuso come rumour da un altro processo che riusa la entry firmata
+	// di node2 come mittente, viene scartato
+	rejected := nodes[0].RejectedMessages()["entry"]
+	ghost, err := json.Marshal(util.GossipMessage{
+		Type:    "rumour",
+		Sender:  nodes[1].membership.Self(),
+		Rumours: []util.NodeStatus{{ID: "ghost", IP: "127.0.0.1", Port: "1", Status: "alive", Incarnation: 1}},
+	})
+	if err != nil {
+		t.Fatalf("rumour: %v", err)
+	}
+	sendUDP(t, seed, string(ghost))
+	waitFor(t, convergeTimeout(config, 3), "la entry inventata deve essere scartata", func() bool {
+		return nodes[0].RejectedMessages()["entry"] > rejected
+	})
+	if _, exists := nodes[0].membership.GetNode("ghost"); exists {
+		t.Fatal("membro inventato entrato nella Membership List")
+	}
+	waitForMembers(t, nodes, 3, convergeTimeout(config, 3))
+}
+
+// ✅ CA di test: certificato autofirmato e chiave
+type testCA struct {
+	certificate *x509.Certificate
+	key         ed25519.PrivateKey
+	file        string // Certificato in PEM
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	t.Helper()
+	_, key, _ := GenerateIdentityKey()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("certificato CA: %v", err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	writePEM(t, file, "CERTIFICATE", der)
+	return testCA{certificate: certificate, key: key, file: file}
+}
+
+// ✅ Scrive in dir certificato e chiave del nodo name (server e client) emessi da ca
+func (ca testCA) issue(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	_, key, _ := GenerateIdentityKey()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, key.Public(), ca.key)
+	if err != nil {
+		t.Fatalf("certificato %s: %v", name, err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("chiave %s: %v", name, err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("scrittura %s: %v", file, err)
+	}
+}
+
+// ✅ Configurazione con TLS mutuo: i file stanno in una directory del nodo, riscrivibile per ReloadTLS
+func tlsConfig(t *testing.T, name string, ca testCA) Config {
+	t.Helper()
+	dir := t.TempDir()
+	config := testConfig(name, freePort(t))
+	config.TLSCertFile, config.TLSKeyFile = ca.issue(t, dir, name)
+	config.TLSCAFile = filepath.Join(dir, "ca.pem")
+	caPEM, _ := os.ReadFile(ca.file)
+	os.WriteFile(config.TLSCAFile, caPEM, 0o600)
+	return config
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t, "cluster-ca")
+	nodes := []*Node{}
+	configs := []Config{}
+	var seed string
+	for i := 1; i <= 3; i++ {
+		config := tlsConfig(t, fmt.Sprintf("node%d", i), ca)
+		node := startNode(t, config)
+		if i == 1 {
+			seed = net.JoinHostPort(config.IP, config.Port)
+		} else if _, err := node.Join([]string{seed}); err != nil {
+			t.Fatalf("Join %s: %v", config.Name, err)
+		}
+		nodes = append(nodes, node)
+		configs = append(configs, config)
+	}
+	waitForMembers(t, nodes, 3, convergeTimeout(configs[0], 3))
+
+	// Senza certificato o con un certificato di un'altra CA il JOIN (su TCP) fallisce
+	otherCA := newTestCA(t, "other-ca")
+	for _, outsider := range []Config{testConfig("outsider1", freePort(t)), tlsConfig(t, "outsider2", otherCA)} {
+		outsider.JoinTimeout = 500 * time.Millisecond
+		if _, err := startNode(t, outsider).Join([]string{seed}); err == nil {
+			t.Errorf("Join di %s riuscito", outsider.Name)
+		}
+	}
+
+	// Un certificato valido non basta: deve essere intestato al nome con cui il nodo si presenta
+	impostor := tlsConfig(t, "outsider3", ca)
+	impostor.Name = "node9"
+	impostor.JoinTimeout = 500 * time.Millisecond
+	if _, err := startNode(t, impostor).Join([]string{seed}); err == nil {
+		t.Error("Join riuscito con il certificato di un altro nodo")
+	}
+	if rejected := nodes[0].RejectedMessages()["join"]; rejected == 0 {
+		t.Error("JOIN con certificato di un altro nodo non conteggiato tra i rifiutati")
+	}
+
+	// Rotazione della CA: nuovi file su tutti i nodi, poi ReloadTLS (come con SIGHUP)
+	for i, config := range configs {
+		dir := filepath.Dir(config.TLSCertFile)
+		otherCA.issue(t, dir, config.Name)
+		caPEM, _ := os.ReadFile(otherCA.file)
+		os.WriteFile(config.TLSCAFile, caPEM, 0o600)
+		if err := nodes[i].ReloadTLS(); err != nil {
+			t.Fatalf("ReloadTLS %s: %v", config.Name, err)
+		}
+	}
+	late := startNode(t, tlsConfig(t, "node4", otherCA))
+	if _, err := late.Join([]string{seed}); err != nil {
+		t.Fatalf("Join con la nuova CA: %v", err)
+	}
+	nodes = append(nodes, late)
+	waitForMembers(t, nodes, 4, convergeTimeout(configs[0], 4))
+}
+
+func TestGracefulLeave(t *testing.T) {
+	nodes, config := startCluster(t, 4)
+
+	leaving := nodes[3]
+	if err := leaving.Leave(time.Second); err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	leaving.Shutdown()
+	remaining := nodes[:3]
+
+	// L'uscita è immediata (nessun sospetto): tombstone LEFT, poi eliminazione
+	waitForStatus(t, remaining, "node4", "left", convergeTimeout(config, 3))
+	waitForMembers(t, remaining, 3, convergeTimeout(config, 3))
+	waitForStatus(t, remaining, "node4", "", config.TombstoneRetention+2*config.FailureCheckInterval)
+}
+
+// ✅ Trasporto che trattiene ogni LEAVE finché gate non viene chiuso
+type blockingLeaveTransport struct {
+	transport.Transport
+	gate     chan struct{}
+	sent     atomic.Int64 // LEAVE inviati
+	inFlight atomic.Int64 // LEAVE in corso di invio
+}
+
+func (b *blockingLeaveTransport) SendTo(address string, data []byte) error {
+	var message struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &message) == nil && message.Type == "leave" {
+		b.inFlight.Add(1)
+		defer b.inFlight.Add(-1)
+		<-b.gate
+		b.sent.Add(1)
+	}
+	return b.Transport.SendTo(address, data)
+}
+
+func TestLeaveTimeoutStopsBroadcast(t *testing.T) {
+	config := testConfig("node1", 7946)
+	memory, _ := transport.NewMemoryNetwork().Listen(net.JoinHostPort(config.IP, config.Port))
+	blocking := &blockingLeaveTransport{Transport: memory, gate: make(chan struct{})}
+	config.Transport = blocking
+	node := startNode(t, config)
+	for i := 2; i <= 4; i++ {
+		node.membership.AddOrUpdateNode(util.NodeStatus{ID: fmt.Sprintf("node%d", i), IP: "127.0.0.1", Port: strconv.Itoa(7946 + i), Status: "alive"})
+	}
+
+	// Il primo LEAVE resta bloccato: Leave scade, ma l'invio in corso appartiene al nodo
+	if err := node.Leave(50 * time.Millisecond); err == nil {
+		t.Fatal("Leave riuscito con l'invio bloccato")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(blocking.gate)
+	}()
+	node.Shutdown()
+
+	// Shutdown attende l'invio in corso, e dopo il timeout non partono altri LEAVE
+	if blocking.inFlight.Load() != 0 {
+		t.Fatal("Shutdown ritornato con un LEAVE ancora in corso di invio")
+	}
+	if sent := blocking.sent.Load(); sent != 1 {
+		t.Fatalf("LEAVE inviati dopo il timeout: %d, atteso solo quello già in corso", sent)
+	}
+}
+
+func TestLeftNodeStaysLeft(t *testing.T) {
+	nodes, config := startCluster(t, 3)
+
+	// Il nodo esce ma resta in esecuzione: il proprio LEFT che torna via gossip
+	// non deve provocare confutazioni né farlo rientrare nel cluster
+	leaving := nodes[2]
+	before := leaving.LocalNode()
+	if err := leaving.Leave(time.Second); err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	remaining := nodes[:2]
+	waitForStatus(t, remaining, "node3", "left", convergeTimeout(config, 3))
+
+	time.Sleep(convergeTimeout(config, 3))
+	self := leaving.membership.Self()
+	if self.Status != "left" || self.Incarnation != before.Incarnation {
+		t.Fatalf("nodo uscito: stato %q incarnation %d, atteso left con incarnation %d", self.Status, self.Incarnation, before.Incarnation)
+	}
+	for _, node := range remaining {
+		if status, _ := node.membership.GetNodeStatus("node3"); status != "left" && status != "" {
+			t.Fatalf("%s vede node3 %q dopo il LEAVE", node.LocalNode().ID, status)
+		}
+	}
+}
+
+func TestForgedLeaveRejected(t *testing.T) {
+	nodes, config, seed := startSignedCluster(t, 3)
+	victim := nodes[1].membership.Self()
+
+	// Un processo estraneo invia LEAVE e ALIVE a nome di node2, senza la sua firma
+	forgedLeave := fmt.Sprintf(`{"type":"leave","sender":%[1]q,"node":{"id":%[1]q,"ip":%[2]q,"port":%[3]q,"status":"left","incarnation":%[4]d}}`, victim.ID, victim.IP, victim.Port, victim.Incarnation)
+	forgedAlive := fmt.Sprintf(`{"type":"alive","sender":{"id":%[1]q,"ip":%[2]q,"port":%[3]q,"status":"alive","incarnation":1000},"membership":[{"id":%[1]q,"ip":%[2]q,"port":%[3]q,"status":"alive","incarnation":1000}]}`, victim.ID, victim.IP, victim.Port)
+	sendUDP(t, seed, `{"type":"leave","sender":"node2"}`, forgedLeave, forgedAlive)
+
+	waitFor(t, convergeTimeout(config, 3), "LEAVE e ALIVE falsificati devono essere rifiutati", func() bool {
+		rejected := nodes[0].RejectedMessages()
+		return rejected["leave"] == 2 && rejected["alive"] == 1
+	})
+	if node, _ := nodes[0].membership.GetNode("node2"); node.Status != "alive" || node.Incarnation >= 1000 {
+		t.Fatalf("node2 dopo i messaggi falsificati: %+v", node)
+	}
+	waitForMembers(t, nodes, 3, convergeTimeout(config, 3))
+}
+
+func TestUnauthenticatedLeaveRejected(t *testing.T) {
+	// Configurazione di default: né keyring né identità
+	nodes, config, seed := startClusterWith(t, 3, func(name string) Config {
+		config := testConfig(name, freePort(t))
+		config.InsecureSelfClaims = DefaultConfig().InsecureSelfClaims
+		return config
+	})
+	victim := nodes[1].membership.Self()
+
+	// Senza firma né chiave nessuno può provare di essere node2: LEAVE, ALIVE e la entry LEFT
+	// in un rumour vengono rifiutati, anche se il formato è quello inviato da node2 stesso
+	left := victim
+	left.Status = "left"
+	leave, err := json.Marshal(util.LeaveMessage{Type: "leave", Sender: victim.ID, Node: left})
+	if err != nil {
+		t.Fatalf("LEAVE: %v", err)
+	}
+	alive := victim
+	alive.Incarnation = 1000
+	refutation, err := json.Marshal(util.GossipMessage{Type: "alive", Sender: alive, Membership: []util.NodeStatus{alive}})
+	if err != nil {
+		t.Fatalf("ALIVE: %v", err)
+	}
+	rumour, err := json.Marshal(util.GossipMessage{Type: "rumour", Sender: nodes[2].membership.Self(), Rumours: []util.NodeStatus{left}})
+	if err != nil {
+		t.Fatalf("rumour: %v", err)
+	}
+	sendUDP(t, seed, string(leave), string(refutation), string(rumour))
+
+	waitFor(t, convergeTimeout(config, 3), "LEAVE, ALIVE e LEFT non autenticati devono essere rifiutati", func() bool {
+		rejected := nodes[0].RejectedMessages()
+		return rejected["leave"] == 1 && rejected["alive"] == 1 && rejected["entry"] >= 1
+	})
+	if node, _ := nodes[0].membership.GetNode("node2"); node.Status != "alive" || node.Incarnation >= 1000 {
+		t.Fatalf("node2 dopo i messaggi non autenticati: %+v", node)
+	}
+	waitForMembers(t, nodes, 3, convergeTimeout(config, 3))
+}
+
+func TestForgedRumourRejected(t *testing.T) {
+	nodes, config, seed := startSignedCluster(t, 3)
+	victim := nodes[1].membership.Self()
+	relay := nodes[2].membership.Self() // Entry firmata di node3, riusabile come mittente da chiunque
+
+	// Entry falsificate di node2: LEFT e ALIVE con incarnation 1000, senza la sua firma
+	left, alive := victim, victim
+	left.Status, left.Incarnation = "left", 1000
+	alive.Incarnation = 1000
+	forged := []util.NodeStatus{left, alive}
+	anonymous := util.NodeStatus{ID: "node3", IP: relay.IP, Port: relay.Port, Status: "alive", Incarnation: 1000}
+
+	packets := []any{
+		// Mittente non firmato: messaggio scartato per intero, compreso l'heartbeat implicito
+		util.GossipMessage{Type: "rumour", Sender: anonymous, Rumours: forged},
+		util.ProbeMessage{Type: "ping", SeqNo: 1, Sender: anonymous, Target: nodes[0].membership.Self(), Rumours: forged},
+		// Mittente autentico (entry riusata): le entry non firmate da node2 vengono scartate
+		util.GossipMessage{Type: "rumour", Sender: relay, Rumours: forged},
+		util.ProbeMessage{Type: "ack", SeqNo: 1, Sender: relay, Rumours: forged},
+	}
+	for _, packet := range packets {
+		data, err := json.Marshal(packet)
+		if err != nil {
+			t.Fatalf("messaggio: %v", err)
+		}
+		sendUDP(t, seed, string(data))
+	}
+
+	// Push-pull su TCP con mittente non firmato, poi con mittente autentico
+	for _, sender := range []util.NodeStatus{anonymous, relay} {
+		data, err := json.Marshal(util.GossipMessage{Type: "gossip_update", Sender: sender, Membership: forged})
+		if err != nil {
+			t.Fatalf("gossip_update: %v", err)
+		}
+		conn, err := net.DialTimeout("tcp", seed, time.Second)
+		if err != nil {
+			t.Fatalf("connessione: %v", err)
+		}
+		conn.SetDeadline(time.Now().Add(time.Second))
+		transport.WriteFrame(conn, data)
+		transport.ReadFrame(conn) // Il mittente non autentico non riceve risposta
+		conn.Close()
+	}
+
+	waitFor(t, convergeTimeout(config, 3), "rumour, probe e push-pull falsificati devono essere rifiutati", func() bool {
+		rejected := nodes[0].RejectedMessages()
+		return rejected["rumour"] == 1 && rejected["ping"] == 1 && rejected["gossip_update"] == 1 && rejected["entry"] >= 6
+	})
+	if node, _ := nodes[0].membership.GetNode("node2"); node.Status != "alive" || node.Incarnation >= 1000 {
+		t.Fatalf("node2 dopo i rumour falsificati: %+v", node)
+	}
+	waitForMembers(t, nodes, 3, convergeTimeout(config, 3))
+}
+
+func TestSignedLeaveFromAnotherAddress(t *testing.T) {
+	nodes, config, seed := startSignedCluster(t, 3)
+
+	// Il LEAVE firmato da node3 arriva da una porta diversa da quella annunciata (come dietro
+	// NAT): l'autenticazione è la firma, non l'indirizzo sorgente, quindi viene accettato
+	left, err := json.Marshal(util.LeaveMessage{Type: "leave", Sender: "node3", Node: nodes[2].membership.MarkSelfLeft()})
+	if err != nil {
+		t.Fatalf("LEAVE: %v", err)
+	}
+	sendUDP(t, seed, string(left))
+
+	waitForStatus(t, nodes[:1], "node3", "left", convergeTimeout(config, 3))
+	if rejected := nodes[0].RejectedMessages(); rejected["leave"] != 0 {
+		t.Fatalf("LEAVE firmato rifiutato: %v", rejected)
+	}
+}
+
+func TestCrashLifecycle(t *testing.T) {
+	nodes, config := startCluster(t, 4)
+
+	// Crash: il nodo si ferma senza LEAVE
+	nodes[3].Shutdown()
+	remaining := nodes[:3]
+
+	// alive → suspect (probe falliti) → dead (DeadTimeout) → eliminato (TombstoneRetention)
+	waitFor(t, suspectTimeout(config, 4), "node4 deve essere sospettato da tutti", func() bool {
+		for _, node := range remaining {
+			if status, _ := node.membership.GetNodeStatus("node4"); status == "alive" {
+				return false
+			}
+		}
+		return true
+	})
+	waitForStatus(t, remaining, "node4", "dead", config.DeadTimeout+2*config.FailureCheckInterval+convergeTimeout(config, 3))
+	waitForMembers(t, remaining, 3, convergeTimeout(config, 3))
+	waitForStatus(t, remaining, "node4", "", config.TombstoneRetention+2*config.FailureCheckInterval)
+}
+
+func TestClusterLifecycle(t *testing.T) {
+	// Crescita graduale da 2 a 5 nodi
+	nodes, config := startCluster(t, 2)
+	seed := net.JoinHostPort(config.IP, nodes[0].LocalNode().Port)
+	configs := map[string]Config{}
+	for i := 3; i <= 5; i++ {
+		nodeConfig := testConfig(fmt.Sprintf("node%d", i), freePort(t))
+		node := startNode(t, nodeConfig)
+		if _, err := node.Join([]string{seed}); err != nil {
+			t.Fatalf("Join %s: %v", nodeConfig.Name, err)
+		}
+		configs[nodeConfig.Name] = nodeConfig
+		nodes = append(nodes, node)
+		waitForMembers(t, nodes, i, convergeTimeout(config, i))
+	}
+
+	// Manutenzione: node3 esce con LEAVE
+	if err := nodes[2].Leave(time.Second); err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	nodes[2].Shutdown()
+	nodes = []*Node{nodes[0], nodes[1], nodes[3], nodes[4]}
+	waitForMembers(t, nodes, 4, convergeTimeout(config, 4))
+
+	// Guasto di node4 e rilevamento
+	nodes[2].Shutdown()
+	survivors := []*Node{nodes[0], nodes[1], nodes[3]}
+	waitForStatus(t, survivors, "node4", "dead", suspectTimeout(config, 4)+config.DeadTimeout+2*config.FailureCheckInterval)
+
+	// Riparazione: node4 riparte con lo stesso nome e la stessa porta e rientra
+	restarted := startNode(t, configs["node4"])
+	if _, err := restarted.Join([]string{seed}); err != nil {
+		t.Fatalf("Join dopo il riavvio: %v", err)
+	}
+	nodes = append(survivors, restarted)
+	waitForMembers(t, nodes, 4, convergeTimeout(config, 4))
+
+	// Ultimo ingresso
+	lateConfig := testConfig("node6", freePort(t))
+	late := startNode(t, lateConfig)
+	if _, err := late.Join([]string{seed}); err != nil {
+		t.Fatalf("Join node6: %v", err)
+	}
+	nodes = append(nodes, late)
+	waitForMembers(t, nodes, 5, convergeTimeout(config, 5))
+}
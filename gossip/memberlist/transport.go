@@ -0,0 +1,16 @@
+package memberlist
+
+import "Gossip/internal/transport"
+
+// ✅ Trasporto di rete del nodo (vedi Config.Transport): pacchetti per probe e rumour,
+// stream per JOIN e push-pull
+type Transport = transport.Transport
+
+// ✅ Rete simulata in memoria: ogni Listen restituisce un Transport per un nodo,
+// così centinaia di nodi possono girare in un solo processo senza socket reali
+type MemoryNetwork = transport.MemoryNetwork
+
+// Costruttore: crea una rete in memoria vuota
+func NewMemoryNetwork() *MemoryNetwork {
+	return transport.NewMemoryNetwork()
+}